@@ -2,8 +2,12 @@ package beater
 
 import (
 	"errors"
+	"net"
+	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"fmt"
@@ -16,8 +20,11 @@ import (
 	"github.com/fsouza/go-dockerclient"
 
 	"github.com/ingensi/dockbeat/calculator"
+	"github.com/ingensi/dockbeat/cgroup"
 	"github.com/ingensi/dockbeat/config"
 	"github.com/ingensi/dockbeat/event"
+	"github.com/ingensi/dockbeat/procfs"
+	"github.com/ingensi/dockbeat/prometheus"
 )
 
 // const for event logs
@@ -29,6 +36,12 @@ const (
 	TRACE = "trace"
 )
 
+// const for stats collectors
+const (
+	DOCKER_COLLECTOR = "docker"
+	CGROUP_COLLECTOR = "cgroup"
+)
+
 type SoftwareVersion struct {
 	major int
 	minor int
@@ -48,6 +61,45 @@ type StatsConfig struct {
 	Memory    bool
 	Blkio     bool
 	Cpu       bool
+	Pids      bool
+	Pressure  bool
+	Tcp       bool
+	Listen    bool
+	Fd        bool
+	Oom       bool
+}
+
+type AggregateConfig struct {
+	Enabled bool
+	GroupBy []string
+	Top     int
+}
+
+type RollupConfig struct {
+	Window     time.Duration
+	Percentile float64
+}
+
+type PrometheusConfig struct {
+	Enabled bool
+	Address string
+	Labels  []string
+}
+
+type SizeConfig struct {
+	Enabled bool
+	Period  time.Duration
+}
+
+type CrashloopConfig struct {
+	Enabled  bool
+	Restarts int
+	Window   time.Duration
+}
+
+type ChangesConfig struct {
+	Enabled   bool
+	Heartbeat time.Duration
 }
 
 type Dockbeat struct {
@@ -55,6 +107,23 @@ type Dockbeat struct {
 	period               time.Duration
 	socketConfig         SocketConfig
 	statsConfig          StatsConfig
+	aggregateConfig      AggregateConfig
+	rollupConfig         RollupConfig
+	changesConfig        ChangesConfig
+	rules                []event.Rule
+	crashloopConfig      CrashloopConfig
+	sizeConfig           SizeConfig
+	prometheusConfig     PrometheusConfig
+	exporter             *prometheus.Exporter
+	collector            string
+	cgroupRoot           string
+	cgroupCollector      *cgroup.Collector
+	procRoot             string
+	sysRoot              string
+	procReader           *procfs.Reader
+	hostMemTotal         int64
+	hostMemTotalLock     sync.Mutex
+	inspected            inspectCache
 	beatConfig           *config.Config
 	dockerClient         *docker.Client
 	events               publisher.Client
@@ -124,6 +193,12 @@ func (bt *Dockbeat) Config(b *beat.Beat) error {
 		Memory:    true,
 		Blkio:     true,
 		Cpu:       true,
+		Pids:      true,
+		Pressure:  true,
+		Tcp:       false,
+		Listen:    false,
+		Fd:        false,
+		Oom:       true,
 	}
 
 	if bt.beatConfig.Dockbeat.Stats.Container != nil && !*bt.beatConfig.Dockbeat.Stats.Container {
@@ -141,6 +216,146 @@ func (bt *Dockbeat) Config(b *beat.Beat) error {
 	if bt.beatConfig.Dockbeat.Stats.Cpu != nil && !*bt.beatConfig.Dockbeat.Stats.Cpu {
 		bt.statsConfig.Cpu = false
 	}
+	if bt.beatConfig.Dockbeat.Stats.Pids != nil && !*bt.beatConfig.Dockbeat.Stats.Pids {
+		bt.statsConfig.Pids = false
+	}
+	if bt.beatConfig.Dockbeat.Stats.Pressure != nil && !*bt.beatConfig.Dockbeat.Stats.Pressure {
+		bt.statsConfig.Pressure = false
+	}
+	if bt.beatConfig.Dockbeat.Stats.Tcp != nil && *bt.beatConfig.Dockbeat.Stats.Tcp {
+		bt.statsConfig.Tcp = true
+	}
+	if bt.beatConfig.Dockbeat.Stats.Listen != nil && *bt.beatConfig.Dockbeat.Stats.Listen {
+		bt.statsConfig.Listen = true
+	}
+	if bt.beatConfig.Dockbeat.Stats.Fd != nil && *bt.beatConfig.Dockbeat.Stats.Fd {
+		bt.statsConfig.Fd = true
+	}
+	if bt.beatConfig.Dockbeat.Stats.Oom != nil && !*bt.beatConfig.Dockbeat.Stats.Oom {
+		bt.statsConfig.Oom = false
+	}
+
+	// init the aggregateConfig
+	bt.aggregateConfig = AggregateConfig{
+		Enabled: false,
+		GroupBy: bt.beatConfig.Dockbeat.Aggregate.GroupBy,
+		Top:     3,
+	}
+	if bt.beatConfig.Dockbeat.Aggregate.Enabled != nil {
+		bt.aggregateConfig.Enabled = *bt.beatConfig.Dockbeat.Aggregate.Enabled
+	}
+	if bt.beatConfig.Dockbeat.Aggregate.Top != nil {
+		bt.aggregateConfig.Top = *bt.beatConfig.Dockbeat.Aggregate.Top
+	}
+
+	// init the rollupConfig
+	bt.rollupConfig = RollupConfig{
+		Window:     0,
+		Percentile: 95,
+	}
+	if bt.beatConfig.Dockbeat.Rollup.Window != nil {
+		bt.rollupConfig.Window = time.Duration(*bt.beatConfig.Dockbeat.Rollup.Window) * time.Second
+	}
+	if bt.beatConfig.Dockbeat.Rollup.Percentile != nil {
+		bt.rollupConfig.Percentile = *bt.beatConfig.Dockbeat.Rollup.Percentile
+	}
+
+	// init the changesConfig
+	bt.changesConfig = ChangesConfig{
+		Enabled:   false,
+		Heartbeat: 0,
+	}
+	if bt.beatConfig.Dockbeat.Changes.Enabled != nil {
+		bt.changesConfig.Enabled = *bt.beatConfig.Dockbeat.Changes.Enabled
+	}
+	if bt.beatConfig.Dockbeat.Changes.Heartbeat != nil {
+		bt.changesConfig.Heartbeat = time.Duration(*bt.beatConfig.Dockbeat.Changes.Heartbeat) * time.Second
+	}
+
+	// init the crashloopConfig
+	bt.crashloopConfig = CrashloopConfig{
+		Enabled:  true,
+		Restarts: 5,
+		Window:   10 * time.Minute,
+	}
+	if bt.beatConfig.Dockbeat.Crashloop.Enabled != nil {
+		bt.crashloopConfig.Enabled = *bt.beatConfig.Dockbeat.Crashloop.Enabled
+	}
+	if bt.beatConfig.Dockbeat.Crashloop.Restarts != nil {
+		bt.crashloopConfig.Restarts = *bt.beatConfig.Dockbeat.Crashloop.Restarts
+	}
+	if bt.beatConfig.Dockbeat.Crashloop.Window != nil {
+		bt.crashloopConfig.Window = time.Duration(*bt.beatConfig.Dockbeat.Crashloop.Window) * time.Second
+	}
+
+	// init the sizeConfig
+	bt.sizeConfig = SizeConfig{
+		Enabled: true,
+		Period:  5 * time.Minute,
+	}
+	if bt.beatConfig.Dockbeat.Size.Enabled != nil {
+		bt.sizeConfig.Enabled = *bt.beatConfig.Dockbeat.Size.Enabled
+	}
+	if bt.beatConfig.Dockbeat.Size.Period != nil {
+		bt.sizeConfig.Period = time.Duration(*bt.beatConfig.Dockbeat.Size.Period) * time.Second
+	}
+
+	// init the prometheusConfig
+	bt.prometheusConfig = PrometheusConfig{
+		Enabled: false,
+		Address: ":9479",
+		Labels:  bt.beatConfig.Dockbeat.Prometheus.Labels,
+	}
+	if bt.beatConfig.Dockbeat.Prometheus.Enabled != nil {
+		bt.prometheusConfig.Enabled = *bt.beatConfig.Dockbeat.Prometheus.Enabled
+	}
+	if bt.beatConfig.Dockbeat.Prometheus.Address != nil {
+		bt.prometheusConfig.Address = *bt.beatConfig.Dockbeat.Prometheus.Address
+	}
+
+	// init the alerting rules
+	bt.rules = []event.Rule{}
+	for _, ruleConfig := range bt.beatConfig.Dockbeat.Rules {
+		name := ""
+		if ruleConfig.Name != nil {
+			name = *ruleConfig.Name
+		}
+		if ruleConfig.Expr == nil {
+			err = errors.New(fmt.Sprintf("Missing expr in rule %v", name))
+			logp.Err("dockbeat", "Error reading configuration file: %v", err)
+			return err
+		}
+		rule, ruleErr := event.ParseRule(name, *ruleConfig.Expr, ruleConfig.Resolve)
+		if ruleErr != nil {
+			err = errors.New(fmt.Sprintf("Invalid rule: %v", ruleErr))
+			logp.Err("dockbeat", "Error reading configuration file: %v", err)
+			return err
+		}
+		bt.rules = append(bt.rules, rule)
+	}
+
+	// init the stats collector
+	bt.collector = DOCKER_COLLECTOR
+	if bt.beatConfig.Dockbeat.Collector != nil {
+		bt.collector = *bt.beatConfig.Dockbeat.Collector
+	}
+	if bt.collector != DOCKER_COLLECTOR && bt.collector != CGROUP_COLLECTOR {
+		err = errors.New(fmt.Sprintf("Unknown collector %v, expected %v or %v", bt.collector, DOCKER_COLLECTOR, CGROUP_COLLECTOR))
+		logp.Err("dockbeat", "Error reading configuration file: %v", err)
+		return err
+	}
+	bt.cgroupRoot = cgroup.DefaultRoot
+	if bt.beatConfig.Dockbeat.Cgroup.Root != nil {
+		bt.cgroupRoot = *bt.beatConfig.Dockbeat.Cgroup.Root
+	}
+	bt.procRoot = procfs.DefaultRoot
+	if bt.beatConfig.Dockbeat.Proc.Root != nil {
+		bt.procRoot = *bt.beatConfig.Dockbeat.Proc.Root
+	}
+	bt.sysRoot = event.DefaultSysRoot
+	if bt.beatConfig.Dockbeat.Sys.Root != nil {
+		bt.sysRoot = *bt.beatConfig.Dockbeat.Sys.Root
+	}
 
 	logp.Info("dockbeat", "Init dockbeat")
 	logp.Info("dockbeat", "Follow docker socket %v\n", bt.socketConfig.socket)
@@ -178,12 +393,40 @@ func (bt *Dockbeat) Setup(b *beat.Beat) error {
 	bt.events = b.Events
 	bt.done = make(chan struct{})
 	bt.dockerClient, clientErr = bt.getDockerClient()
+	// the cgroup filesystem is also used with the docker collector, for the metrics docker does not provide
+	bt.cgroupCollector = cgroup.NewCollector(bt.cgroupRoot)
+	bt.procReader = procfs.NewReader(bt.procRoot)
+	if bt.collector == CGROUP_COLLECTOR {
+		if memTotal, err := bt.procReader.MemTotal(); err == nil {
+			bt.cgroupCollector.MemTotal = memTotal
+		} else {
+			logp.Warn("Unable to read the host memory, memory limits of containers without limit are not capped: %v", err)
+		}
+	}
+	if bt.prometheusConfig.Enabled {
+		bt.exporter = prometheus.NewExporter(bt.prometheusConfig.Labels)
+	}
 	bt.eventGenerator = &event.EventGenerator{
 		Socket:            &bt.socketConfig.socket,
 		NetworkStats:      event.EGNetworkStats{M: map[string]map[string]calculator.NetworkData{}},
+		CpuStats:          event.EGCpuStats{M: map[string]calculator.CPUData{}},
 		BlkioStats:        event.EGBlkioStats{M: map[string]calculator.BlkioData{}},
+		BlockDevices:      event.EGBlockDevices{Root: bt.sysRoot},
 		CalculatorFactory: calculator.CalculatorFactoryImpl{},
 		Period:            bt.period,
+		Rollup: event.EGRollup{
+			Window:     bt.rollupConfig.Window,
+			Percentile: bt.rollupConfig.Percentile,
+		},
+		ContainerChanges: event.EGContainerChanges{
+			Enabled:   bt.changesConfig.Enabled,
+			Heartbeat: bt.changesConfig.Heartbeat,
+		},
+		Rules: event.EGRules{Rules: bt.rules},
+		CrashLoops: event.EGCrashLoops{
+			Restarts: bt.crashloopConfig.Restarts,
+			Window:   bt.crashloopConfig.Window,
+		},
 	}
 
 	if clientErr != nil {
@@ -199,6 +442,19 @@ func (bt *Dockbeat) Run(b *beat.Beat) error {
 	ticker := time.NewTicker(bt.period)
 	defer ticker.Stop()
 
+	if bt.statsConfig.Oom || bt.inspectNeeded() {
+		go bt.watchDaemonEvents()
+	}
+	if bt.sizeConfig.Enabled && bt.sizeConfig.Period > 0 {
+		go bt.collectContainerSizes()
+	}
+	if bt.exporter != nil {
+		if err = bt.serveMetrics(); err != nil {
+			logp.Err("Unable to serve the prometheus metrics: %v", err)
+			return err
+		}
+	}
+
 	for {
 		select {
 		case <-bt.done:
@@ -226,6 +482,89 @@ func (bt *Dockbeat) Run(b *beat.Beat) error {
 	}
 }
 
+/*
+watchDaemonEvents follows the events of the docker daemon until dockbeat stops: it publishes an oom event for each
+daemon oom event and for each die event of a container killed by the OOM killer, and refreshes the inspected
+containers which changed. Containers are inspected at each tick while the events can't be followed.
+*/
+func (d *Dockbeat) watchDaemonEvents() {
+	listener := make(chan *docker.APIEvents, 10)
+	if err := d.dockerClient.AddEventListener(listener); err != nil {
+		logp.Warn("Unable to listen to docker events, daemon oom events are ignored: %v", err)
+		d.publishLogEvent(WARN, fmt.Sprintf("Unable to listen to docker events, daemon oom events are ignored: %v", err))
+		return
+	}
+	defer d.dockerClient.RemoveEventListener(listener)
+	d.inspected.setEnabled(true)
+	defer d.inspected.setEnabled(false)
+
+	for {
+		select {
+		case <-d.done:
+			return
+		case daemonEvent, ok := <-listener:
+			if !ok {
+				return
+			}
+			d.inspected.update(daemonEvent)
+			if !d.statsConfig.Oom {
+				continue
+			}
+			if oomEvent := d.eventGenerator.GetDaemonOomEvent(daemonEvent); oomEvent != nil {
+				d.publishEvents([]common.MapStr{oomEvent})
+			}
+			// API >= 1.22 events have an action and an actor, older ones a status and an id
+			if daemonEvent.Action == "die" {
+				d.publishExitedOomEvent(daemonEvent.Actor.ID)
+			} else if daemonEvent.Action == "" && daemonEvent.Status == "die" {
+				d.publishExitedOomEvent(daemonEvent.ID)
+			}
+		}
+	}
+}
+
+// collectContainerSizes lists the containers with their sizes at each size period, until dockbeat stops. It runs
+// apart from the ticks as the daemon computes the size of every container layer.
+func (d *Dockbeat) collectContainerSizes() {
+	ticker := time.NewTicker(d.sizeConfig.Period)
+	defer ticker.Stop()
+
+	for {
+		logp.Debug("dockbeat", "getting list of containers with sizes")
+		containers, err := d.dockerClient.ListContainers(docker.ListContainersOptions{Size: true})
+		if err == nil {
+			d.eventGenerator.UpdateContainerSizes(time.Now(), containers)
+		} else {
+			logp.Warn("Unable to get the container sizes: %v", err)
+			d.publishLogEvent(WARN, fmt.Sprintf("Unable to get the container sizes: %v", err))
+		}
+
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// serveMetrics serves the prometheus metrics on /metrics in background, until dockbeat stops
+func (d *Dockbeat) serveMetrics() error {
+	listener, err := net.Listen("tcp", d.prometheusConfig.Address)
+	if err != nil {
+		return err
+	}
+	logp.Info("Serving prometheus metrics on %v/metrics", listener.Addr())
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", d.exporter)
+	go func() {
+		<-d.done
+		listener.Close()
+	}()
+	go http.Serve(listener, mux)
+	return nil
+}
+
 func (d *Dockbeat) Cleanup(b *beat.Beat) error {
 	return nil
 }
@@ -241,21 +580,92 @@ func (d *Dockbeat) RunOneTime(b *beat.Beat) error {
 
 	if err == nil {
 		logp.Debug("dockbeat", "got %v containers", len(containers))
+		tickTime := time.Now()
+		var wg sync.WaitGroup
+		tickEvents := make(chan []common.MapStr, len(containers))
 		//export stats for each container
 		for _, container := range containers {
-			d.exportContainerStats(container)
+			wg.Add(1)
+			d.exportContainerStats(container, &wg, tickEvents)
+		}
+		// wait for all container events in background, to publish the host and group aggregates
+		go func() {
+			wg.Wait()
+			close(tickEvents)
+			d.publishAggregateEvents(tickTime, containers, tickEvents)
+		}()
+
+		// the saved stats are only cleaned with a successful container list, a failed list would forget them all
+		live := event.ContainerIDs(containers)
+		if alerts := d.eventGenerator.CleanOldStats(live); len(alerts) > 0 {
+			d.events.PublishEvents(alerts)
+		}
+		d.cgroupCollector.Clean(live)
+		d.inspected.clean(live)
+		if d.exporter != nil {
+			d.exporter.Clean(live)
 		}
 	} else {
 		logp.Err("dockbeat", "Cannot get container list: %v", err)
 		d.publishLogEvent(ERROR, fmt.Sprintf("Cannot get container list: %v", err))
 	}
 
-	d.eventGenerator.CleanOldStats(containers)
-
 	return nil
 }
 
-func (d *Dockbeat) exportContainerStats(container docker.APIContainers) error {
+/*
+publishExitedOomEvent inspects a sampled container which died, before its memory sample is cleaned: docker only
+reports the OOM kill in the state of the container until it is started again.
+*/
+func (d *Dockbeat) publishExitedOomEvent(id string) {
+	if !d.eventGenerator.HasMemorySample(id) {
+		return
+	}
+	info, err := d.inspectContainer(id)
+	if err != nil {
+		logp.Warn("Unable to inspect container %v: %v", id, err)
+		d.publishLogEvent(WARN, fmt.Sprintf("Unable to inspect container %v: %v", id, err))
+		return
+	}
+	if event := d.eventGenerator.GetExitedOomEvent(&docker.APIContainers{ID: id}, info); event != nil {
+		d.publishEvents([]common.MapStr{event})
+	}
+}
+
+func (d *Dockbeat) publishAggregateEvents(tickTime time.Time, containers []docker.APIContainers, tickEvents <-chan []common.MapStr) {
+	if !d.aggregateConfig.Enabled {
+		return
+	}
+
+	events := []common.MapStr{}
+	for containerEvents := range tickEvents {
+		events = append(events, containerEvents...)
+	}
+
+	logp.Debug("dockbeat", "generating aggregate events for %v containers", len(containers))
+	aggregates := d.eventGenerator.GetAggregateEvents(tickTime, containers, events, d.aggregateConfig.GroupBy, d.aggregateConfig.Top)
+	d.publishEvents(aggregates)
+}
+
+// publishEvents publishes events, or the rollup events of the ended windows when the rollup mode is enabled,
+// and the alerts of the rules they fire or resolve
+func (d *Dockbeat) publishEvents(events []common.MapStr) {
+	// rules are evaluated on every sample, before the rollup
+	events = append(events, d.eventGenerator.EvaluateRules(events)...)
+	events = d.eventGenerator.RollupEvents(time.Now(), events)
+	if len(events) == 0 {
+		return
+	}
+	logp.Info("dockbeat", "Publishing %v events", len(events))
+	d.events.PublishEvents(events)
+}
+
+// getContainerStats gets the stats of a container from the configured collector
+func (d *Dockbeat) getContainerStats(container docker.APIContainers) (*docker.Stats, error) {
+	if d.collector == CGROUP_COLLECTOR {
+		return d.cgroupCollector.Stats(container.ID)
+	}
+
 	// statsOptions creation
 	statsC := make(chan *docker.Stats)
 	done := make(chan bool)
@@ -273,10 +683,18 @@ func (d *Dockbeat) exportContainerStats(container docker.APIContainers) error {
 		errC <- d.dockerClient.Stats(statsOptions)
 		close(errC)
 	}()
+	stats := <-statsC
+	err := <-errC
+	return stats, err
+}
+
+// exportContainerStats gets and publishes the stats of a container.
+// When done, published events are sent to tickEvents and wg is notified.
+func (d *Dockbeat) exportContainerStats(container docker.APIContainers, wg *sync.WaitGroup, tickEvents chan<- []common.MapStr) error {
 	// goroutine to get the stats & publish it
 	go func() {
-		stats := <-statsC
-		err := <-errC
+		defer wg.Done()
+		stats, err := d.getContainerStats(container)
 
 		if err == nil && stats != nil {
 			events := []common.MapStr{}
@@ -285,8 +703,10 @@ func (d *Dockbeat) exportContainerStats(container docker.APIContainers) error {
 
 			if d.statsConfig.Container {
 				logp.Debug("dockbeat", "generating container event for %v", container.ID)
-				events = append(events, d.eventGenerator.GetContainerEvent(&container, stats))
-				logp.Debug("dockbeat", "container event append to event list (container %v)", container.ID)
+				if containerEvent := d.eventGenerator.GetChangedContainerEvent(&container, stats); containerEvent != nil {
+					events = append(events, containerEvent)
+					logp.Debug("dockbeat", "container event append to event list (container %v)", container.ID)
+				}
 			}
 
 			if d.statsConfig.Cpu {
@@ -296,18 +716,110 @@ func (d *Dockbeat) exportContainerStats(container docker.APIContainers) error {
 
 			}
 
+			if d.statsConfig.Blkio {
+				logp.Debug("dockbeat", "generating blkio event for %v", container.ID)
+				events = append(events, d.eventGenerator.GetBlkioEvent(&container, stats))
+				logp.Debug("dockbeat", "container blkio append to event list (container %v)", container.ID)
+
+			}
+
+			// the memory and pids limits, the oom killed state, the restarts and the container main process are only
+			// available by inspecting the container
+			var info *docker.Container
+			if d.inspectNeeded() {
+				var inspectErr error
+				info, inspectErr = d.inspectContainer(container.ID)
+				if inspectErr != nil {
+					logp.Warn("Unable to inspect container %v: %v", container.ID, inspectErr)
+					d.publishLogEvent(WARN, fmt.Sprintf("Unable to inspect container %v: %v", container.ID, inspectErr))
+				}
+			}
+
 			if d.statsConfig.Memory {
 				logp.Debug("dockbeat", "generating memory event for %v", container.ID)
-				events = append(events, d.eventGenerator.GetMemoryEvent(&container, stats))
+				memoryEvent := d.eventGenerator.GetMemoryEvent(&container, stats)
+				// memory.events are only available with the cgroup v2 collector
+				if d.collector == CGROUP_COLLECTOR {
+					if memoryEvents := d.cgroupCollector.MemoryEvents(container.ID); memoryEvents != nil {
+						d.eventGenerator.AddMemoryEvents(memoryEvent, memoryEvents)
+					}
+				}
+				// the memory limit is only known by inspecting the container
+				if info != nil {
+					d.eventGenerator.AddMemoryLimit(memoryEvent, stats, info, d.getHostMemTotal())
+				}
+				events = append(events, memoryEvent)
 				logp.Debug("dockbeat", "container memory append to event list (container %v)", container.ID)
 
 			}
 
-			if d.statsConfig.Blkio {
-				logp.Debug("dockbeat", "generating blkio event for %v", container.ID)
-				events = append(events, d.eventGenerator.GetBlkioEvent(&container, stats))
-				logp.Debug("dockbeat", "container blkio append to event list (container %v)", container.ID)
+			if d.statsConfig.Oom {
+				logp.Debug("dockbeat", "generating oom events for %v", container.ID)
+				events = append(events, d.eventGenerator.GetOomEvents(&container, stats, info)...)
+				logp.Debug("dockbeat", "container oom append to event list (container %v)", container.ID)
+			}
+
+			if d.crashloopConfig.Enabled && info != nil {
+				if crashloopEvent := d.eventGenerator.GetCrashLoopEvent(&container, stats, info); crashloopEvent != nil {
+					events = append(events, crashloopEvent)
+					logp.Debug("dockbeat", "container crashloop append to event list (container %v)", container.ID)
+				}
+			}
+
+			if d.statsConfig.Pids && info != nil {
+				logp.Debug("dockbeat", "generating pids event for %v", container.ID)
+				events = append(events, d.eventGenerator.GetPidsEvent(&container, stats, info))
+				logp.Debug("dockbeat", "container pids append to event list (container %v)", container.ID)
+			}
+
+			// the sockets are read from the network namespace of the container main process
+			if d.statsConfig.Tcp && info != nil && info.State.Pid > 0 {
+				sockets, tcpErr := d.procReader.TCPSockets(info.State.Pid)
+				if tcpErr == nil {
+					logp.Debug("dockbeat", "generating tcp event for %v", container.ID)
+					events = append(events, d.eventGenerator.GetTcpEvent(&container, stats, sockets))
+					logp.Debug("dockbeat", "container tcp append to event list (container %v)", container.ID)
+				} else {
+					logp.Warn("Unable to read tcp sockets of container %v: %v", container.ID, tcpErr)
+					d.publishLogEvent(WARN, fmt.Sprintf("Unable to read tcp sockets of container %v: %v", container.ID, tcpErr))
+				}
+			}
+
+			if d.statsConfig.Listen && info != nil && info.State.Pid > 0 {
+				sockets, listenErr := d.procReader.ListeningSockets(info.State.Pid)
+				if listenErr == nil {
+					logp.Debug("dockbeat", "generating listen event for %v", container.ID)
+					events = append(events, d.eventGenerator.GetListenEvent(&container, stats, sockets))
+					logp.Debug("dockbeat", "container listen append to event list (container %v)", container.ID)
+				} else {
+					logp.Warn("Unable to read listening sockets of container %v: %v", container.ID, listenErr)
+					d.publishLogEvent(WARN, fmt.Sprintf("Unable to read listening sockets of container %v: %v", container.ID, listenErr))
+				}
+			}
 
+			if d.statsConfig.Fd {
+				processes, fdErr := d.getFileDescriptors(container)
+				if fdErr == nil {
+					logp.Debug("dockbeat", "generating fd event for %v", container.ID)
+					events = append(events, d.eventGenerator.GetFdEvent(&container, stats, processes))
+					logp.Debug("dockbeat", "container fd append to event list (container %v)", container.ID)
+				} else {
+					logp.Warn("Unable to read file descriptors of container %v: %v", container.ID, fdErr)
+					d.publishLogEvent(WARN, fmt.Sprintf("Unable to read file descriptors of container %v: %v", container.ID, fdErr))
+				}
+			}
+
+			if d.statsConfig.Pressure {
+				// pressure stall information is only available with cgroup v2
+				pressure, err := d.cgroupCollector.Pressure(container.ID)
+				if err != nil {
+					logp.Warn("Unable to read pressure of container %v: %v", container.ID, err)
+					d.publishLogEvent(WARN, fmt.Sprintf("Unable to read pressure of container %v: %v", container.ID, err))
+				} else if pressure != nil {
+					logp.Debug("dockbeat", "generating pressure event for %v", container.ID)
+					events = append(events, d.eventGenerator.GetPressureEvent(&container, pressure))
+					logp.Debug("dockbeat", "container pressure append to event list (container %v)", container.ID)
+				}
 			}
 
 			if d.statsConfig.Net {
@@ -317,8 +829,11 @@ func (d *Dockbeat) exportContainerStats(container docker.APIContainers) error {
 
 			}
 
-			logp.Info("dockbeat", "Publishing %v events", len(events))
-			d.events.PublishEvents(events)
+			if d.exporter != nil {
+				d.exporter.Update(&container, events)
+			}
+			d.publishEvents(events)
+			tickEvents <- events
 		} else if err == nil && stats == nil {
 			logp.Warn("dockbeat", "Container was existing at listing but not when getting statistics: %v", container.ID)
 			d.publishLogEvent(WARN, fmt.Sprintf("Container was existing at listing but not when getting statistics: %v", container.ID))
@@ -331,6 +846,98 @@ func (d *Dockbeat) exportContainerStats(container docker.APIContainers) error {
 	return nil
 }
 
+// inspectNeeded returns true when the enabled statistics need the inspected containers
+func (d *Dockbeat) inspectNeeded() bool {
+	return d.statsConfig.Memory || d.statsConfig.Pids || d.statsConfig.Tcp || d.statsConfig.Listen || d.statsConfig.Oom ||
+		d.crashloopConfig.Enabled
+}
+
+// inspectContainer returns the inspected container, from the cache when it did not change since it was inspected
+func (d *Dockbeat) inspectContainer(id string) (*docker.Container, error) {
+	info, ok, generation := d.inspected.get(id)
+	if ok {
+		return info, nil
+	}
+	info, err := d.dockerClient.InspectContainer(id)
+	if err != nil {
+		return nil, err
+	}
+	d.inspected.set(id, info, generation)
+	return info, nil
+}
+
+// getHostMemTotal returns the host total memory, from the docker daemon information. It is cached as it does not
+// change, and 0 is returned when it cannot be retrieved.
+func (d *Dockbeat) getHostMemTotal() int64 {
+	d.hostMemTotalLock.Lock()
+	defer d.hostMemTotalLock.Unlock()
+	if d.hostMemTotal == 0 {
+		info, err := d.dockerClient.Info()
+		if err != nil {
+			logp.Warn("Unable to get docker daemon information: %v", err)
+			return 0
+		}
+		d.hostMemTotal = info.MemTotal
+	}
+	return d.hostMemTotal
+}
+
+// getContainerPids returns the PIDs of the container processes, from the container cgroup or, when the cgroup
+// cannot be found or read (e.g. wrong cgroup.root or missing permissions), from the docker top API
+func (d *Dockbeat) getContainerPids(container docker.APIContainers) ([]int, error) {
+	pids, err := d.cgroupCollector.Pids(container.ID)
+	if err == nil && pids != nil {
+		return pids, nil
+	}
+	if err != nil {
+		logp.Debug("dockbeat", "Unable to read the cgroup processes of container %v, using docker top: %v", container.ID, err)
+	}
+
+	top, err := d.dockerClient.TopContainer(container.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	column := -1
+	for i, title := range top.Titles {
+		if title == "PID" {
+			column = i
+			break
+		}
+	}
+	if column < 0 {
+		return nil, errors.New("no PID column in docker top result")
+	}
+	pids = []int{}
+	for _, process := range top.Processes {
+		if column < len(process) {
+			if pid, err := strconv.Atoi(process[column]); err == nil {
+				pids = append(pids, pid)
+			}
+		}
+	}
+	return pids, nil
+}
+
+// getFileDescriptors returns the file descriptors usage of each process of the container
+func (d *Dockbeat) getFileDescriptors(container docker.APIContainers) ([]procfs.FileDescriptors, error) {
+	pids, err := d.getContainerPids(container)
+	if err != nil {
+		return nil, err
+	}
+	processes := []procfs.FileDescriptors{}
+	for _, pid := range pids {
+		fds, err := d.procReader.FileDescriptors(pid)
+		if os.IsNotExist(err) {
+			// the process exited since the listing
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		processes = append(processes, fds)
+	}
+	return processes, nil
+}
+
 func (d *Dockbeat) checkPrerequisites() error {
 	var output error = nil
 
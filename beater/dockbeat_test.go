@@ -2,11 +2,19 @@ package beater
 
 import (
 	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/fsouza/go-dockerclient"
 	"github.com/ingensi/dockbeat/calculator"
+	"github.com/ingensi/dockbeat/cgroup"
 	"github.com/ingensi/dockbeat/config"
 	"github.com/ingensi/dockbeat/event"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -138,6 +146,38 @@ func TestDockerClientGetterWithTCPPath(t *testing.T) {
 // TODO write test for TLS docker client instantiation
 
 // helper method
+// PIDS TESTS
+
+func TestDockbeatGetContainerPidsCgroupErrorFallback(t *testing.T) {
+	// GIVEN
+	// the container cgroup exists but its processes can't be read
+	root, err := ioutil.TempDir("", "dockbeat")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "cpuacct", "docker", "abc123"), 0755))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/abc123/top" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Titles":["UID","PID","CMD"],"Processes":[["root","42","nginx"],["www","43","nginx"]]}`))
+	}))
+	defer server.Close()
+	dockbeat := getEmptyDockbeat()
+	dockbeat.cgroupCollector = cgroup.NewCollector(root)
+	dockbeat.dockerClient, err = docker.NewClient(server.URL)
+	assert.Nil(t, err)
+
+	// WHEN
+	pids, err := dockbeat.getContainerPids(docker.APIContainers{ID: "abc123"})
+
+	// THEN
+	// the PIDs are given by the docker top API
+	assert.Nil(t, err)
+	assert.Equal(t, []int{42, 43}, pids)
+}
+
 func getEmptyDockbeat() Dockbeat {
 	return Dockbeat{
 		done:   make(chan struct{}),
@@ -155,6 +195,12 @@ func getEmptyDockbeat() Dockbeat {
 			Net:       true,
 			Blkio:     true,
 			Memory:    true,
+			Pids:      true,
+			Pressure:  true,
+			Tcp:       false,
+			Listen:    false,
+			Fd:        false,
+			Oom:       true,
 		},
 		beatConfig: &config.Config{
 			Dockbeat: config.DockbeatConfig{
@@ -172,6 +218,12 @@ func getEmptyDockbeat() Dockbeat {
 					Net:       nil,
 					Blkio:     nil,
 					Memory:    nil,
+					Pids:      nil,
+					Pressure:  nil,
+					Tcp:       nil,
+					Listen:    nil,
+					Fd:        nil,
+					Oom:       nil,
 				},
 			},
 		},
@@ -179,6 +231,7 @@ func getEmptyDockbeat() Dockbeat {
 		events:       nil,
 		eventGenerator: &event.EventGenerator{
 			NetworkStats:      event.EGNetworkStats{M: map[string]map[string]calculator.NetworkData{}},
+			CpuStats:          event.EGCpuStats{M: map[string]calculator.CPUData{}},
 			BlkioStats:        event.EGBlkioStats{M: map[string]calculator.BlkioData{}},
 			CalculatorFactory: calculator.CalculatorFactoryImpl{},
 			Period:            time.Second,
@@ -186,3 +239,50 @@ func getEmptyDockbeat() Dockbeat {
 		minimalDockerVersion: SoftwareVersion{major: 1, minor: 5},
 	}
 }
+
+// OOM TESTS
+
+// publishedEvents is a publisher client which keeps the published events
+type publishedEvents struct {
+	events []common.MapStr
+}
+
+func (p *publishedEvents) PublishEvent(event common.MapStr, opts ...publisher.ClientOption) bool {
+	p.events = append(p.events, event)
+	return true
+}
+
+func (p *publishedEvents) PublishEvents(events []common.MapStr, opts ...publisher.ClientOption) bool {
+	p.events = append(p.events, events...)
+	return true
+}
+
+func TestDockbeatPublishExitedOomEvent(t *testing.T) {
+	// GIVEN
+	// a sampled container which died, killed by the OOM killer
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Id":"abc123","State":{"OOMKilled":true,"ExitCode":137,"FinishedAt":"2016-06-01T10:00:00Z"}}`))
+	}))
+	defer server.Close()
+	dockbeat := getEmptyDockbeat()
+	var err error
+	dockbeat.dockerClient, err = docker.NewClient(server.URL)
+	assert.Nil(t, err)
+	published := &publishedEvents{}
+	dockbeat.events = published
+	stats := &docker.Stats{Read: time.Now()}
+	stats.MemoryStats.Usage, stats.MemoryStats.Limit = 1000, 1024
+	dockbeat.eventGenerator.GetOomEvents(&docker.APIContainers{ID: "abc123", Names: []string{"/web"}}, stats, nil)
+
+	// WHEN
+	dockbeat.publishExitedOomEvent("abc123")
+	// containers which were not sampled are not inspected
+	dockbeat.publishExitedOomEvent("unknown")
+
+	// THEN
+	assert.Equal(t, 1, len(published.events))
+	assert.Equal(t, "oom", published.events[0]["type"])
+	assert.Equal(t, "web", published.events[0]["containerName"])
+	assert.Equal(t, event.OomSourceInspect, published.events[0]["oom"].(common.MapStr)["source"])
+}
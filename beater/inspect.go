@@ -0,0 +1,84 @@
+package beater
+
+import (
+	"sync"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// inspectEvents are the daemon events after which the inspected limits, main process or state of a container change
+var inspectEvents = map[string]bool{
+	"start":   true,
+	"restart": true,
+	"die":     true,
+	"oom":     true,
+	"update":  true,
+	"destroy": true,
+}
+
+/*
+inspectCache keeps the inspected containers, to avoid inspecting every container at each tick. The memory and pids
+limits and the main process only change when a container is updated or started, and the state checked for OOM kills
+and crash loops when it dies: an inspected container is kept until one of the inspectEvents is received for it.
+
+The cache is only enabled while the daemon events are followed, otherwise missed events would leave stale entries.
+*/
+type inspectCache struct {
+	sync.Mutex
+	enabled bool
+	m       map[string]*docker.Container
+	// generation changes at each event, so that an inspect started before the event is not cached
+	generation uint64
+}
+
+func (c *inspectCache) setEnabled(enabled bool) {
+	c.Lock()
+	defer c.Unlock()
+	c.enabled = enabled
+	c.m = map[string]*docker.Container{}
+	c.generation++
+}
+
+// get returns the cached container, if any, and the generation to give to set once inspected
+func (c *inspectCache) get(id string) (*docker.Container, bool, uint64) {
+	c.Lock()
+	defer c.Unlock()
+	info, ok := c.m[id]
+	return info, ok && c.enabled, c.generation
+}
+
+// set caches an inspected container, unless the cache is disabled or an event was received since the given generation
+func (c *inspectCache) set(id string, info *docker.Container, generation uint64) {
+	c.Lock()
+	defer c.Unlock()
+	if c.enabled && generation == c.generation {
+		c.m[id] = info
+	}
+}
+
+// update removes the container of a daemon event from the cache, when the event changes its inspected data
+func (c *inspectCache) update(daemonEvent *docker.APIEvents) {
+	// API >= 1.22 events have an action and an actor, older ones a status and an id
+	action, id := daemonEvent.Action, daemonEvent.Actor.ID
+	if action == "" {
+		action, id = daemonEvent.Status, daemonEvent.ID
+	}
+	if !inspectEvents[action] {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	delete(c.m, id)
+	c.generation++
+}
+
+// clean removes the containers which are gone
+func (c *inspectCache) clean(live map[string]bool) {
+	c.Lock()
+	defer c.Unlock()
+	for id := range c.m {
+		if !live[id] {
+			delete(c.m, id)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package beater
+
+import (
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDockbeatInspectContainerCache(t *testing.T) {
+	// GIVEN
+	inspects := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inspects++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Id":"abc123","State":{"Running":true,"Pid":42}}`))
+	}))
+	defer server.Close()
+	dockbeat := getEmptyDockbeat()
+	var err error
+	dockbeat.dockerClient, err = docker.NewClient(server.URL)
+	assert.Nil(t, err)
+	dockbeat.inspected.setEnabled(true)
+
+	// WHEN
+	first, firstErr := dockbeat.inspectContainer("abc123")
+	cached, _ := dockbeat.inspectContainer("abc123")
+	// an exec event does not change the container, a die event does
+	dockbeat.inspected.update(&docker.APIEvents{Action: "exec_start", Actor: docker.APIActor{ID: "abc123"}})
+	dockbeat.inspectContainer("abc123")
+	dockbeat.inspected.update(&docker.APIEvents{Action: "die", Actor: docker.APIActor{ID: "abc123"}})
+	dockbeat.inspectContainer("abc123")
+
+	// THEN
+	assert.Nil(t, firstErr)
+	assert.Equal(t, 42, first.State.Pid)
+	assert.True(t, first == cached)
+	assert.Equal(t, 2, inspects)
+}
+
+func TestInspectCacheDisabled(t *testing.T) {
+	// GIVEN
+	// the daemon events are not followed
+	cache := inspectCache{}
+	_, _, generation := cache.get("abc123")
+
+	// WHEN
+	cache.set("abc123", &docker.Container{ID: "abc123"}, generation)
+	_, ok, _ := cache.get("abc123")
+
+	// THEN
+	assert.False(t, ok)
+}
+
+func TestInspectCacheEventDuringInspect(t *testing.T) {
+	// GIVEN
+	cache := inspectCache{}
+	cache.setEnabled(true)
+	_, _, generation := cache.get("abc123")
+
+	// WHEN
+	// the container dies while it is inspected, with an older API event
+	cache.update(&docker.APIEvents{Status: "die", ID: "abc123"})
+	cache.set("abc123", &docker.Container{ID: "abc123"}, generation)
+	_, ok, _ := cache.get("abc123")
+
+	// THEN
+	// the possibly outdated container is not cached
+	assert.False(t, ok)
+}
+
+func TestInspectCacheClean(t *testing.T) {
+	// GIVEN
+	cache := inspectCache{}
+	cache.setEnabled(true)
+	cache.set("abc123", &docker.Container{ID: "abc123"}, cache.generation)
+	cache.set("def456", &docker.Container{ID: "def456"}, cache.generation)
+
+	// WHEN
+	cache.clean(map[string]bool{"def456": true})
+
+	// THEN
+	_, removed, _ := cache.get("abc123")
+	_, kept, _ := cache.get("def456")
+	assert.False(t, removed)
+	assert.True(t, kept)
+}
@@ -8,6 +8,14 @@ type BlkioCalculator interface {
 	GetReadPs() float64
 	GetWritePs() float64
 	GetTotalPs() float64
+	GetReadBytesPs() float64
+	GetWriteBytesPs() float64
+	GetTotalBytesPs() float64
+	GetAvgServiceTime() float64
+	GetAvgWaitTime() float64
+	GetMergedPs() float64
+	GetSectorsPs() float64
+	CounterReset() bool
 }
 
 type BlkioCalculatorImpl struct {
@@ -16,10 +24,24 @@ type BlkioCalculatorImpl struct {
 }
 
 type BlkioData struct {
-	Time   time.Time
-	Reads  uint64
-	Writes uint64
-	Totals uint64
+	Time       time.Time
+	Reads      uint64
+	Writes     uint64
+	Totals     uint64
+	ReadBytes  uint64
+	WriteBytes uint64
+	TotalBytes uint64
+
+	// ServiceTime and WaitTime are cumulated times spent by operations, in nanoseconds
+	ServiceTime uint64
+	WaitTime    uint64
+	// Queued is the number of operations currently queued (not a counter)
+	Queued  uint64
+	Merged  uint64
+	Sectors uint64
+
+	// Devices contains the same counters split by device, keyed by "major:minor"
+	Devices map[string]BlkioData
 }
 
 func (c BlkioCalculatorImpl) GetReadPs() float64 {
@@ -34,7 +56,65 @@ func (c BlkioCalculatorImpl) GetTotalPs() float64 {
 	return c.calculatePerSecond(c.Old.Totals, c.New.Totals)
 }
 
+func (c BlkioCalculatorImpl) GetReadBytesPs() float64 {
+	return c.calculatePerSecond(c.Old.ReadBytes, c.New.ReadBytes)
+}
+
+func (c BlkioCalculatorImpl) GetWriteBytesPs() float64 {
+	return c.calculatePerSecond(c.Old.WriteBytes, c.New.WriteBytes)
+}
+
+func (c BlkioCalculatorImpl) GetTotalBytesPs() float64 {
+	return c.calculatePerSecond(c.Old.TotalBytes, c.New.TotalBytes)
+}
+
+// GetAvgServiceTime returns the average time in milliseconds the device took to serve an operation during the period.
+func (c BlkioCalculatorImpl) GetAvgServiceTime() float64 {
+	return c.calculatePerOperation(c.Old.ServiceTime, c.New.ServiceTime)
+}
+
+// GetAvgWaitTime returns the average time in milliseconds an operation waited in the scheduler queues during the period.
+func (c BlkioCalculatorImpl) GetAvgWaitTime() float64 {
+	return c.calculatePerOperation(c.Old.WaitTime, c.New.WaitTime)
+}
+
+func (c BlkioCalculatorImpl) GetMergedPs() float64 {
+	return c.calculatePerSecond(c.Old.Merged, c.New.Merged)
+}
+
+func (c BlkioCalculatorImpl) GetSectorsPs() float64 {
+	return c.calculatePerSecond(c.Old.Sectors, c.New.Sectors)
+}
+
+// CounterReset returns true when one of the counters has been reset since the old data (e.g. container restart)
+func (c BlkioCalculatorImpl) CounterReset() bool {
+	return c.rate().AnyReset(
+		[2]uint64{c.Old.Reads, c.New.Reads},
+		[2]uint64{c.Old.Writes, c.New.Writes},
+		[2]uint64{c.Old.Totals, c.New.Totals},
+		[2]uint64{c.Old.ReadBytes, c.New.ReadBytes},
+		[2]uint64{c.Old.WriteBytes, c.New.WriteBytes},
+		[2]uint64{c.Old.TotalBytes, c.New.TotalBytes},
+		[2]uint64{c.Old.ServiceTime, c.New.ServiceTime},
+		[2]uint64{c.Old.WaitTime, c.New.WaitTime},
+		[2]uint64{c.Old.Merged, c.New.Merged},
+		[2]uint64{c.Old.Sectors, c.New.Sectors},
+	)
+}
+
+func (c BlkioCalculatorImpl) rate() Rate {
+	return NewRate(c.Old.Time, c.New.Time)
+}
+
+func (c BlkioCalculatorImpl) calculatePerOperation(oldValue uint64, newValue uint64) float64 {
+	rate := c.rate()
+	operations := rate.Delta(c.Old.Totals, c.New.Totals)
+	if operations == 0 || rate.Reset(oldValue, newValue) {
+		return 0
+	}
+	return float64(newValue-oldValue) / float64(operations) / float64(time.Millisecond)
+}
+
 func (c BlkioCalculatorImpl) calculatePerSecond(oldValue uint64, newValue uint64) float64 {
-	duration := c.New.Time.Sub(c.Old.Time)
-	return float64(newValue-oldValue) / duration.Seconds()
+	return c.rate().PerSecond(oldValue, newValue)
 }
@@ -89,3 +89,226 @@ func TestBlkioTotal(t *testing.T) {
 	// value should be (3030-3000)/2
 	assert.Equal(t, float64(15), value)
 }
+
+func TestBlkioReadBytes(t *testing.T) {
+	// GIVEN
+	oldTimestamp := time.Now()
+	newTimestamp := oldTimestamp.Add(2 * time.Second)
+
+	old := BlkioData{
+		Time:       oldTimestamp,
+		ReadBytes:  4096,
+		WriteBytes: 8192,
+		TotalBytes: 12288,
+	}
+	new := BlkioData{
+		Time:       newTimestamp,
+		ReadBytes:  8192,
+		WriteBytes: 16384,
+		TotalBytes: 24576,
+	}
+
+	var calculator = BlkioCalculatorImpl{old, new}
+
+	// WHEN
+	value := calculator.GetReadBytesPs()
+
+	// THEN
+	// value should be (8192-4096)/2
+	assert.Equal(t, float64(2048), value)
+}
+
+func TestBlkioWriteBytes(t *testing.T) {
+	// GIVEN
+	oldTimestamp := time.Now()
+	newTimestamp := oldTimestamp.Add(2 * time.Second)
+
+	old := BlkioData{
+		Time:       oldTimestamp,
+		ReadBytes:  4096,
+		WriteBytes: 8192,
+		TotalBytes: 12288,
+	}
+	new := BlkioData{
+		Time:       newTimestamp,
+		ReadBytes:  8192,
+		WriteBytes: 16384,
+		TotalBytes: 24576,
+	}
+
+	var calculator = BlkioCalculatorImpl{old, new}
+
+	// WHEN
+	value := calculator.GetWriteBytesPs()
+
+	// THEN
+	// value should be (16384-8192)/2
+	assert.Equal(t, float64(4096), value)
+}
+
+func TestBlkioTotalBytes(t *testing.T) {
+	// GIVEN
+	oldTimestamp := time.Now()
+	newTimestamp := oldTimestamp.Add(2 * time.Second)
+
+	old := BlkioData{
+		Time:       oldTimestamp,
+		ReadBytes:  4096,
+		WriteBytes: 8192,
+		TotalBytes: 12288,
+	}
+	new := BlkioData{
+		Time:       newTimestamp,
+		ReadBytes:  8192,
+		WriteBytes: 16384,
+		TotalBytes: 24576,
+	}
+
+	var calculator = BlkioCalculatorImpl{old, new}
+
+	// WHEN
+	value := calculator.GetTotalBytesPs()
+
+	// THEN
+	// value should be (24576-12288)/2
+	assert.Equal(t, float64(6144), value)
+}
+
+func TestBlkioAvgServiceTime(t *testing.T) {
+	// GIVEN
+	oldTimestamp := time.Now()
+	newTimestamp := oldTimestamp.Add(2 * time.Second)
+
+	old := BlkioData{
+		Time:        oldTimestamp,
+		Totals:      100,
+		ServiceTime: 50000000,
+		WaitTime:    100000000,
+	}
+	new := BlkioData{
+		Time:        newTimestamp,
+		Totals:      110,
+		ServiceTime: 80000000,
+		WaitTime:    150000000,
+	}
+
+	var calculator = BlkioCalculatorImpl{old, new}
+
+	// WHEN
+	value := calculator.GetAvgServiceTime()
+
+	// THEN
+	// value should be (80ms-50ms)/(110-100)
+	assert.Equal(t, float64(3), value)
+}
+
+func TestBlkioAvgWaitTime(t *testing.T) {
+	// GIVEN
+	oldTimestamp := time.Now()
+	newTimestamp := oldTimestamp.Add(2 * time.Second)
+
+	old := BlkioData{
+		Time:        oldTimestamp,
+		Totals:      100,
+		ServiceTime: 50000000,
+		WaitTime:    100000000,
+	}
+	new := BlkioData{
+		Time:        newTimestamp,
+		Totals:      110,
+		ServiceTime: 80000000,
+		WaitTime:    150000000,
+	}
+
+	var calculator = BlkioCalculatorImpl{old, new}
+
+	// WHEN
+	value := calculator.GetAvgWaitTime()
+
+	// THEN
+	// value should be (150ms-100ms)/(110-100)
+	assert.Equal(t, float64(5), value)
+}
+
+func TestBlkioAvgServiceTimeWithoutOperation(t *testing.T) {
+	// GIVEN
+	oldTimestamp := time.Now()
+	newTimestamp := oldTimestamp.Add(2 * time.Second)
+
+	old := BlkioData{
+		Time:        oldTimestamp,
+		Totals:      100,
+		ServiceTime: 50000000,
+	}
+	new := BlkioData{
+		Time:        newTimestamp,
+		Totals:      100,
+		ServiceTime: 50000000,
+	}
+
+	var calculator = BlkioCalculatorImpl{old, new}
+
+	// WHEN
+	value := calculator.GetAvgServiceTime()
+
+	// THEN
+	// no operation during the period
+	assert.Equal(t, float64(0), value)
+}
+
+func TestBlkioMergedAndSectors(t *testing.T) {
+	// GIVEN
+	oldTimestamp := time.Now()
+	newTimestamp := oldTimestamp.Add(2 * time.Second)
+
+	old := BlkioData{
+		Time:    oldTimestamp,
+		Merged:  10,
+		Sectors: 1000,
+	}
+	new := BlkioData{
+		Time:    newTimestamp,
+		Merged:  30,
+		Sectors: 3000,
+	}
+
+	var calculator = BlkioCalculatorImpl{old, new}
+
+	// WHEN
+	merged := calculator.GetMergedPs()
+	sectors := calculator.GetSectorsPs()
+
+	// THEN
+	assert.Equal(t, float64(10), merged)
+	assert.Equal(t, float64(1000), sectors)
+}
+
+func TestBlkioCounterReset(t *testing.T) {
+	// GIVEN
+	oldTimestamp := time.Now()
+	newTimestamp := oldTimestamp.Add(2 * time.Second)
+
+	// container restarted, counters went back to small values
+	old := BlkioData{
+		Time:        oldTimestamp,
+		Reads:       1000,
+		Totals:      1000,
+		ReadBytes:   4096000,
+		ServiceTime: 50000000,
+	}
+	new := BlkioData{
+		Time:        newTimestamp,
+		Reads:       10,
+		Totals:      10,
+		ReadBytes:   40960,
+		ServiceTime: 500000,
+	}
+
+	var calculator = BlkioCalculatorImpl{old, new}
+
+	// WHEN / THEN
+	assert.Equal(t, float64(0), calculator.GetReadPs())
+	assert.Equal(t, float64(0), calculator.GetReadBytesPs())
+	assert.Equal(t, float64(0), calculator.GetAvgServiceTime())
+	assert.True(t, calculator.CounterReset())
+}
@@ -3,6 +3,7 @@ package calculator
 import (
 	"github.com/elastic/beats/libbeat/common"
 	"strconv"
+	"time"
 )
 
 type CPUCalculator interface {
@@ -10,6 +11,7 @@ type CPUCalculator interface {
 	TotalUsage() float64
 	UsageInKernelmode() float64
 	UsageInUsermode() float64
+	CounterReset() bool
 }
 
 type CPUCalculatorImpl struct {
@@ -22,6 +24,7 @@ type CPUData struct {
 	TotalUsage        uint64
 	UsageInKernelmode uint64
 	UsageInUsermode   uint64
+	Time              time.Time
 }
 
 func (c CPUCalculatorImpl) PerCpuUsage() common.MapStr {
@@ -47,15 +50,22 @@ func (c CPUCalculatorImpl) UsageInUsermode() float64 {
 	return c.calculateLoad(c.New.UsageInUsermode, c.Old.UsageInUsermode)
 }
 
-func (c CPUCalculatorImpl) calculateLoad(oldValue uint64, newValue uint64) float64 {
-	// value is the count of CPU nanosecond in 1sec
-	// TODO save the old stat timestamp and reuse here in case of docker read time changes...
+// CounterReset returns true when one of the counters has been reset since the old data (e.g. container restart)
+func (c CPUCalculatorImpl) CounterReset() bool {
+	return c.rate().AnyReset(
+		[2]uint64{c.Old.TotalUsage, c.New.TotalUsage},
+		[2]uint64{c.Old.UsageInKernelmode, c.New.UsageInKernelmode},
+		[2]uint64{c.Old.UsageInUsermode, c.New.UsageInUsermode},
+	)
+}
+
+func (c CPUCalculatorImpl) rate() Rate {
+	return NewRate(c.Old.Time, c.New.Time)
+}
+
+func (c CPUCalculatorImpl) calculateLoad(newValue uint64, oldValue uint64) float64 {
+	// value is the count of CPU nanosecond per second
 	// 1s = 1000000000 ns
 	// value / 1000000000
-
-	value := int64(oldValue - newValue)
-	if value < 0 {
-		value = 0
-	}
-	return float64(value) / float64(1000000000)
+	return c.rate().PerSecond(oldValue, newValue) / float64(time.Second)
 }
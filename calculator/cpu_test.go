@@ -4,12 +4,16 @@ import (
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
+var oldTime = time.Date(2016, 6, 1, 10, 0, 0, 0, time.UTC)
+var newTime = oldTime.Add(time.Second)
+
 func TestCPUperCpuUsage(t *testing.T) {
 	// GIVEN
-	var oldData = CPUData{[]uint64{1, 2, 3, 4}, 0, 0, 0}
-	var newData = CPUData{[]uint64{100000001, 200000002, 300000003, 400000004}, 0, 0, 0}
+	var oldData = CPUData{[]uint64{1, 2, 3, 4}, 0, 0, 0, oldTime}
+	var newData = CPUData{[]uint64{100000001, 200000002, 300000003, 400000004}, 0, 0, 0, newTime}
 	var calculator = CPUCalculatorImpl{oldData, newData}
 
 	// WHEN
@@ -27,8 +31,8 @@ func TestCPUperCpuUsage(t *testing.T) {
 
 func TestCPUperCpuUsageAvoidMassiveValues(t *testing.T) {
 	// GIVEN
-	var oldData = CPUData{[]uint64{1, 2, 3, 4}, 0, 0, 0}
-	var newData = CPUData{[]uint64{0, 1, 2, 3}, 0, 0, 0}
+	var oldData = CPUData{[]uint64{1, 2, 3, 4}, 0, 0, 0, oldTime}
+	var newData = CPUData{[]uint64{0, 1, 2, 3}, 0, 0, 0, newTime}
 	var calculator = CPUCalculatorImpl{oldData, newData}
 
 	// WHEN
@@ -46,8 +50,8 @@ func TestCPUperCpuUsageAvoidMassiveValues(t *testing.T) {
 
 func TestCPUTotalUsage(t *testing.T) {
 	// GIVEN
-	var oldData = CPUData{nil, 50, 0, 0}
-	var newData = CPUData{nil, 500000050, 0, 0}
+	var oldData = CPUData{nil, 50, 0, 0, oldTime}
+	var newData = CPUData{nil, 500000050, 0, 0, newTime}
 	var calculator = CPUCalculatorImpl{oldData, newData}
 
 	// WHEN
@@ -60,8 +64,8 @@ func TestCPUTotalUsage(t *testing.T) {
 
 func TestCPUTotalUsageAvoidMassiveValues(t *testing.T) {
 	// GIVEN
-	var oldData = CPUData{nil, 55, 0, 0}
-	var newData = CPUData{nil, 5, 0, 0}
+	var oldData = CPUData{nil, 55, 0, 0, oldTime}
+	var newData = CPUData{nil, 5, 0, 0, newTime}
 	var calculator = CPUCalculatorImpl{oldData, newData}
 
 	// WHEN
@@ -74,8 +78,8 @@ func TestCPUTotalUsageAvoidMassiveValues(t *testing.T) {
 
 func TestCPUUsageInKernelmode(t *testing.T) {
 	// GIVEN
-	var oldData = CPUData{nil, 0, 0, 0}
-	var newData = CPUData{nil, 0, 800000000, 0}
+	var oldData = CPUData{nil, 0, 0, 0, oldTime}
+	var newData = CPUData{nil, 0, 800000000, 0, newTime}
 	var calculator = CPUCalculatorImpl{oldData, newData}
 
 	// WHEN
@@ -88,8 +92,8 @@ func TestCPUUsageInKernelmode(t *testing.T) {
 
 func TestCPUUsageInKernelmodeAvoidMassiveValues(t *testing.T) {
 	// GIVEN
-	var oldData = CPUData{nil, 0, 1, 0}
-	var newData = CPUData{nil, 0, 0, 0}
+	var oldData = CPUData{nil, 0, 1, 0, oldTime}
+	var newData = CPUData{nil, 0, 0, 0, newTime}
 	var calculator = CPUCalculatorImpl{oldData, newData}
 
 	// WHEN
@@ -102,8 +106,8 @@ func TestCPUUsageInKernelmodeAvoidMassiveValues(t *testing.T) {
 
 func TestCPUUsageInUsermode(t *testing.T) {
 	// GIVEN
-	var oldData = CPUData{nil, 0, 0, 0}
-	var newData = CPUData{nil, 0, 0, 800000000}
+	var oldData = CPUData{nil, 0, 0, 0, oldTime}
+	var newData = CPUData{nil, 0, 0, 800000000, newTime}
 	var calculator = CPUCalculatorImpl{oldData, newData}
 
 	// WHEN
@@ -116,8 +120,8 @@ func TestCPUUsageInUsermode(t *testing.T) {
 
 func TestCPUUsageInUsermodeAvoidMassiveValues(t *testing.T) {
 	// GIVEN
-	var oldData = CPUData{nil, 0, 0, 1}
-	var newData = CPUData{nil, 0, 0, 0}
+	var oldData = CPUData{nil, 0, 0, 1, oldTime}
+	var newData = CPUData{nil, 0, 0, 0, newTime}
 	var calculator = CPUCalculatorImpl{oldData, newData}
 
 	// WHEN
@@ -127,3 +131,44 @@ func TestCPUUsageInUsermodeAvoidMassiveValues(t *testing.T) {
 	// value should be 0%
 	assert.Equal(t, float64(0), value)
 }
+
+func TestCPUCounterReset(t *testing.T) {
+	// GIVEN
+	var oldData = CPUData{nil, 55, 0, 0, oldTime}
+	var newData = CPUData{nil, 5, 0, 0, newTime}
+	var calculator = CPUCalculatorImpl{oldData, newData}
+
+	// WHEN
+	reset := calculator.CounterReset()
+
+	// THEN
+	assert.True(t, reset)
+}
+
+func TestCPUTotalUsageMeasuredInterval(t *testing.T) {
+	// GIVEN
+	var oldData = CPUData{nil, 50, 0, 0, oldTime}
+	var newData = CPUData{nil, 1000000050, 0, 0, oldTime.Add(2 * time.Second)}
+	var calculator = CPUCalculatorImpl{oldData, newData}
+
+	// WHEN
+	value := calculator.TotalUsage()
+
+	// THEN
+	// one second of CPU over two seconds, value should be 50%
+	assert.Equal(t, 0.50, value)
+}
+
+func TestCPUTotalUsageInvalidInterval(t *testing.T) {
+	// GIVEN
+	var oldData = CPUData{nil, 50, 0, 0, oldTime}
+	var newData = CPUData{nil, 500000050, 0, 0, oldTime}
+	var calculator = CPUCalculatorImpl{oldData, newData}
+
+	// WHEN
+	value := calculator.TotalUsage()
+
+	// THEN
+	// no rate without elapsed time
+	assert.Equal(t, 0.0, value)
+}
@@ -56,3 +56,15 @@ func (_m *CPUCalculator) UsageInUsermode() float64 {
 
 	return r0
 }
+func (_m *CPUCalculator) CounterReset() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
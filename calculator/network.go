@@ -13,6 +13,7 @@ type NetworkCalculator interface {
 	GetTxDroppedPerSecond() float64
 	GetTxErrorsPerSecond() float64
 	GetTxPacketsPerSecond() float64
+	CounterReset() bool
 }
 
 type NetworkCalculatorImpl struct {
@@ -64,7 +65,24 @@ func (c NetworkCalculatorImpl) GetTxPacketsPerSecond() float64 {
 	return c.calculatePerSecond(c.old.TxPackets, c.new.TxPackets)
 }
 
+// CounterReset returns true when one of the counters has been reset since the old data (e.g. container restart)
+func (c NetworkCalculatorImpl) CounterReset() bool {
+	return c.rate().AnyReset(
+		[2]uint64{c.old.RxBytes, c.new.RxBytes},
+		[2]uint64{c.old.RxDropped, c.new.RxDropped},
+		[2]uint64{c.old.RxErrors, c.new.RxErrors},
+		[2]uint64{c.old.RxPackets, c.new.RxPackets},
+		[2]uint64{c.old.TxBytes, c.new.TxBytes},
+		[2]uint64{c.old.TxDropped, c.new.TxDropped},
+		[2]uint64{c.old.TxErrors, c.new.TxErrors},
+		[2]uint64{c.old.TxPackets, c.new.TxPackets},
+	)
+}
+
+func (c NetworkCalculatorImpl) rate() Rate {
+	return NewRate(c.old.Time, c.new.Time)
+}
+
 func (c NetworkCalculatorImpl) calculatePerSecond(oldValue uint64, newValue uint64) float64 {
-	duration := c.new.Time.Sub(c.old.Time)
-	return float64((newValue - oldValue)) / duration.Seconds()
+	return c.rate().PerSecond(oldValue, newValue)
 }
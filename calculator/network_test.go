@@ -165,3 +165,40 @@ func TestNetworkGetTxPacketsPerSecond(t *testing.T) {
 	// value should be 0 packets / second when old value > new value
 	assert.Equal(t, float64(1.2), value)
 }
+
+func TestNetworkCounterReset(t *testing.T) {
+	// GIVEN
+	var oldDate = time.Now()
+	var newDate = oldDate.Add(time.Second)
+
+	// container restarted, rxBytes counter went from 1000 to 10
+	var oldData = NetworkData{oldDate, 1000, 0, 0, 0, 0, 0, 0, 0}
+	var newData = NetworkData{newDate, 10, 0, 0, 0, 0, 0, 0, 0}
+	var calculator = NetworkCalculatorImpl{oldData, newData}
+
+	// WHEN
+	value := calculator.GetRxBytesPerSecond()
+
+	// THEN
+	// value should be 0 and the reset should be reported
+	assert.Equal(t, float64(0), value)
+	assert.True(t, calculator.CounterReset())
+}
+
+func TestNetworkInvalidInterval(t *testing.T) {
+	// GIVEN
+	var date = time.Now()
+
+	// both samples have been read at the same time
+	var oldData = NetworkData{date, 10, 0, 0, 0, 0, 0, 0, 0}
+	var newData = NetworkData{date, 110, 0, 0, 0, 0, 0, 0, 0}
+	var calculator = NetworkCalculatorImpl{oldData, newData}
+
+	// WHEN
+	value := calculator.GetRxBytesPerSecond()
+
+	// THEN
+	// no division by zero
+	assert.Equal(t, float64(0), value)
+	assert.False(t, calculator.CounterReset())
+}
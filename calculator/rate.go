@@ -0,0 +1,52 @@
+package calculator
+
+import (
+	"time"
+)
+
+// Rate computes per second rates of monotonic counters sampled at two points in time.
+// Every calculator relies on it, so that counter resets and invalid intervals are handled the same way for all metrics.
+type Rate struct {
+	Duration time.Duration
+}
+
+func NewRate(oldTime time.Time, newTime time.Time) Rate {
+	return Rate{Duration: newTime.Sub(oldTime)}
+}
+
+// Valid returns false when no rate can be computed over the interval (zero or negative duration).
+func (r Rate) Valid() bool {
+	return r.Duration > 0
+}
+
+// Reset returns true when the counter went backwards between the two samples, which happens when
+// the container restarted or the counter wrapped around.
+func (r Rate) Reset(oldValue uint64, newValue uint64) bool {
+	return newValue < oldValue
+}
+
+// Delta returns how much the counter increased, or 0 when it has been reset.
+func (r Rate) Delta(oldValue uint64, newValue uint64) uint64 {
+	if r.Reset(oldValue, newValue) {
+		return 0
+	}
+	return newValue - oldValue
+}
+
+// PerSecond returns the counter increase per second, or 0 when the interval is invalid or the counter has been reset.
+func (r Rate) PerSecond(oldValue uint64, newValue uint64) float64 {
+	if !r.Valid() {
+		return 0
+	}
+	return float64(r.Delta(oldValue, newValue)) / r.Duration.Seconds()
+}
+
+// AnyReset returns true if at least one of the given old/new counter pairs has been reset.
+func (r Rate) AnyReset(values ...[2]uint64) bool {
+	for _, value := range values {
+		if r.Reset(value[0], value[1]) {
+			return true
+		}
+	}
+	return false
+}
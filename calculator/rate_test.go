@@ -0,0 +1,68 @@
+package calculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRatePerSecond(t *testing.T) {
+	// GIVEN
+	oldTimestamp := time.Now()
+	newTimestamp := oldTimestamp.Add(4 * time.Second)
+	rate := NewRate(oldTimestamp, newTimestamp)
+
+	// WHEN
+	value := rate.PerSecond(100, 300)
+
+	// THEN
+	// value should be (300-100)/4
+	assert.Equal(t, float64(50), value)
+	assert.True(t, rate.Valid())
+	assert.False(t, rate.Reset(100, 300))
+}
+
+func TestRatePerSecondAfterReset(t *testing.T) {
+	// GIVEN
+	// the counter restarted from zero (e.g. container restart)
+	oldTimestamp := time.Now()
+	newTimestamp := oldTimestamp.Add(time.Second)
+	rate := NewRate(oldTimestamp, newTimestamp)
+
+	// WHEN
+	value := rate.PerSecond(1000, 10)
+
+	// THEN
+	// no bogus value (uint64 underflow) should be computed
+	assert.Equal(t, float64(0), value)
+	assert.True(t, rate.Reset(1000, 10))
+	assert.Equal(t, uint64(0), rate.Delta(1000, 10))
+}
+
+func TestRatePerSecondInvalidInterval(t *testing.T) {
+	// GIVEN
+	// new sample read at the same time or before the old sample
+	timestamp := time.Now()
+	rates := []Rate{
+		NewRate(timestamp, timestamp),
+		NewRate(timestamp, timestamp.Add(-time.Second)),
+	}
+
+	for _, rate := range rates {
+		// WHEN
+		value := rate.PerSecond(10, 20)
+
+		// THEN
+		assert.False(t, rate.Valid())
+		assert.Equal(t, float64(0), value)
+	}
+}
+
+func TestRateAnyReset(t *testing.T) {
+	// GIVEN
+	rate := Rate{Duration: time.Second}
+
+	// WHEN / THEN
+	assert.False(t, rate.AnyReset([2]uint64{1, 2}, [2]uint64{3, 3}))
+	assert.True(t, rate.AnyReset([2]uint64{1, 2}, [2]uint64{3, 0}))
+}
@@ -0,0 +1,268 @@
+// Package cgroup reads container statistics directly from the cgroup filesystem, as an alternative to the
+// docker stats API which takes about one second per container and loads the daemon.
+package cgroup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+const DefaultRoot = "/sys/fs/cgroup"
+
+// unlimited is the limit reported by cgroup v1 when no limit is set (the highest page aligned int64)
+const unlimited = uint64(9223372036854771712)
+
+// Collector builds docker.Stats from the cgroup accounting files, so that events are generated exactly as with
+// the docker stats API. Network statistics are not available in cgroups.
+type Collector struct {
+	sync.Mutex
+	// Root is the cgroup filesystem mount point, e.g. /host/sys/fs/cgroup when running inside a container
+	Root string
+	// MemTotal is the host total memory, which docker reports as the memory limit of containers without limit.
+	// The limit is not capped when it is 0.
+	MemTotal uint64
+	// previous contains the last cpu sample of each container, used to build the PreCPUStats
+	previous map[string]cpuSample
+}
+
+type cpuSample struct {
+	time  time.Time
+	stats docker.CPUStats
+}
+
+func NewCollector(root string) *Collector {
+	if root == "" {
+		root = DefaultRoot
+	}
+	return &Collector{Root: root, previous: map[string]cpuSample{}}
+}
+
+/*
+Stats returns the statistics of the given container. It returns nil stats and no error when the container cgroup
+does not exist (e.g. the container has been removed since it was listed), like the docker stats API.
+
+The docker stats API samples the cpu usage twice, one second apart. To avoid waiting, the PreCPUStats are
+extrapolated from the previous call: they are the cpu counters one second before, at the average rate observed
+since the previous call.
+*/
+func (c *Collector) Stats(id string) (*docker.Stats, error) {
+	stats := &docker.Stats{Read: time.Now()}
+	if c.Version() == 2 {
+		path := c.v2Path(id)
+		if path == "" {
+			return nil, nil
+		}
+		if err := readV2(path, stats); err != nil {
+			return nil, err
+		}
+	} else {
+		paths := c.v1Paths(id)
+		if paths == nil {
+			return nil, nil
+		}
+		if err := readV1(paths, stats); err != nil {
+			return nil, err
+		}
+	}
+
+	// like the docker daemon, the memory limit can't be higher than the host memory
+	if c.MemTotal > 0 && stats.MemoryStats.Limit > c.MemTotal {
+		stats.MemoryStats.Limit = c.MemTotal
+	}
+	stats.PreCPUStats = c.preCPUStats(id, stats.Read, stats.CPUStats)
+	return stats, nil
+}
+
+// Version returns 2 when the root is a cgroup v2 unified hierarchy, 1 otherwise
+func (c *Collector) Version() int {
+	if exists(filepath.Join(c.Root, "cgroup.controllers")) {
+		return 2
+	}
+	return 1
+}
+
+/*
+MemoryEvents returns the memory.events counters of the given container (low, high, max, oom, oom_kill), which are
+only available with cgroup v2. It returns nil when they are not available.
+*/
+func (c *Collector) MemoryEvents(id string) map[string]uint64 {
+	if c.Version() != 2 {
+		return nil
+	}
+	path := c.v2Path(id)
+	if path == "" {
+		return nil
+	}
+	values, err := readKeyValues(filepath.Join(path, "memory.events"))
+	if err != nil {
+		return nil
+	}
+	return values
+}
+
+/*
+Pids returns the PIDs of the processes of the given container, read from the cgroup.procs file of its cgroup.
+The tasks file is not used as it lists threads, which share the resources of their process (e.g. file descriptors).
+It returns nil when the container cgroup cannot be found.
+*/
+func (c *Collector) Pids(id string) ([]int, error) {
+	var path string
+	if c.Version() == 2 {
+		path = c.v2Path(id)
+	} else if paths := c.v1Paths(id); paths != nil {
+		path = paths.cpuacct
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	lines, err := readLines(filepath.Join(path, "cgroup.procs"))
+	if err != nil {
+		return nil, err
+	}
+	pids := make([]int, 0, len(lines))
+	for _, fields := range lines {
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+// Clean removes the saved cpu samples of the containers which are not in the given set of live container IDs
+func (c *Collector) Clean(live map[string]bool) {
+	c.Lock()
+	defer c.Unlock()
+	for id := range c.previous {
+		if !live[id] {
+			delete(c.previous, id)
+		}
+	}
+}
+
+func (c *Collector) preCPUStats(id string, read time.Time, current docker.CPUStats) docker.CPUStats {
+	c.Lock()
+	previous, ok := c.previous[id]
+	if c.previous == nil {
+		c.previous = map[string]cpuSample{}
+	}
+	c.previous[id] = cpuSample{time: read, stats: current}
+	c.Unlock()
+
+	if !ok {
+		// no usage can be computed on the first sample
+		return current
+	}
+	elapsed := read.Sub(previous.time).Seconds()
+	if elapsed <= 0 {
+		return current
+	}
+
+	pre := docker.CPUStats{}
+	pre.CPUUsage.TotalUsage = oneSecondBefore(previous.stats.CPUUsage.TotalUsage, current.CPUUsage.TotalUsage, elapsed)
+	pre.CPUUsage.UsageInKernelmode = oneSecondBefore(previous.stats.CPUUsage.UsageInKernelmode, current.CPUUsage.UsageInKernelmode, elapsed)
+	pre.CPUUsage.UsageInUsermode = oneSecondBefore(previous.stats.CPUUsage.UsageInUsermode, current.CPUUsage.UsageInUsermode, elapsed)
+	if len(previous.stats.CPUUsage.PercpuUsage) == len(current.CPUUsage.PercpuUsage) {
+		pre.CPUUsage.PercpuUsage = make([]uint64, len(current.CPUUsage.PercpuUsage))
+		for i := range current.CPUUsage.PercpuUsage {
+			pre.CPUUsage.PercpuUsage[i] = oneSecondBefore(previous.stats.CPUUsage.PercpuUsage[i], current.CPUUsage.PercpuUsage[i], elapsed)
+		}
+	}
+	return pre
+}
+
+// oneSecondBefore returns the counter value one second before the current one, at the average rate since the
+// previous value. Reset counters are returned as is so that the reset is detected by the calculators.
+func oneSecondBefore(previous uint64, current uint64, elapsed float64) uint64 {
+	if current < previous {
+		return previous
+	}
+	return current - uint64(float64(current-previous)/elapsed)
+}
+
+// readUint reads a file containing a single number
+func readUint(path string) (uint64, error) {
+	content, err := readFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return parseUint(strings.TrimSpace(content))
+}
+
+// parseUint parses a number; "max", used by cgroup v2 when there is no limit, is returned as the value
+// reported by cgroup v1 in the same case, so that both are capped to the host memory by Stats
+func parseUint(value string) (uint64, error) {
+	if value == "max" {
+		return unlimited, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+func readFile(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	return string(content), err
+}
+
+// readLines returns the fields of each line of a file, ignoring empty lines
+func readLines(path string) ([][]string, error) {
+	content, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := [][]string{}
+	for _, line := range strings.Split(content, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			lines = append(lines, fields)
+		}
+	}
+	return lines, nil
+}
+
+// readKeyValues reads files made of "key value" lines, such as memory.stat or cpu.stat
+func readKeyValues(path string) (map[string]uint64, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]uint64{}
+	for _, fields := range lines {
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := parseUint(fields[1]); err == nil {
+			values[fields[0]] = value
+		}
+	}
+	return values, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// optional ignores errors due to missing files, as some controllers or files are not always available
+func optional(err error) error {
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// containerDirs returns the cgroup directories a docker container can be found in, relative to a hierarchy,
+// with the cgroupfs and systemd cgroup drivers
+func containerDirs(id string) []string {
+	return []string{
+		filepath.Join("docker", id),
+		filepath.Join("system.slice", "docker-"+id+".scope"),
+	}
+}
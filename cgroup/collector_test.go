@@ -0,0 +1,255 @@
+package cgroup
+
+import (
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCollectorStatsV1(t *testing.T) {
+	// GIVEN
+	collector := NewCollector("testdata/v1")
+
+	// WHEN
+	stats, err := collector.Stats("abc123")
+
+	// THEN
+	assert.Nil(t, err)
+	assert.NotNil(t, stats)
+	assert.False(t, stats.Read.IsZero())
+
+	// cpu, user and system times are converted from USER_HZ to nanoseconds
+	assert.Equal(t, uint64(5000000000), stats.CPUStats.CPUUsage.TotalUsage)
+	assert.Equal(t, []uint64{3000000000, 2000000000}, stats.CPUStats.CPUUsage.PercpuUsage)
+	assert.Equal(t, uint64(3000000000), stats.CPUStats.CPUUsage.UsageInUsermode)
+	assert.Equal(t, uint64(1500000000), stats.CPUStats.CPUUsage.UsageInKernelmode)
+	// first sample: no usage can be computed
+	assert.Equal(t, stats.CPUStats, stats.PreCPUStats)
+
+	// memory
+	assert.Equal(t, uint64(104857600), stats.MemoryStats.Usage)
+	assert.Equal(t, uint64(209715200), stats.MemoryStats.MaxUsage)
+	assert.Equal(t, uint64(2), stats.MemoryStats.Failcnt)
+	assert.Equal(t, uint64(536870912), stats.MemoryStats.Limit)
+	assert.Equal(t, uint64(52428800), stats.MemoryStats.Stats.TotalRss)
+	assert.Equal(t, uint64(1048576), stats.MemoryStats.Stats.Cache)
+	assert.Equal(t, uint64(3), stats.MemoryStats.Stats.TotalPgmafault)
+
+	// blkio, the cgroup total lines are ignored
+	assert.Equal(t, []docker.BlkioStatsEntry{
+		{Major: 8, Minor: 0, Op: "Read", Value: 100},
+		{Major: 8, Minor: 0, Op: "Write", Value: 200},
+		{Major: 8, Minor: 0, Op: "Sync", Value: 0},
+		{Major: 8, Minor: 0, Op: "Async", Value: 300},
+		{Major: 8, Minor: 0, Op: "Total", Value: 300},
+	}, stats.BlkioStats.IOServicedRecursive)
+	assert.Equal(t, 5, len(stats.BlkioStats.IOServiceBytesRecursive))
+	assert.Equal(t, []docker.BlkioStatsEntry{{Major: 8, Minor: 0, Op: "Total", Value: 300000000}}, stats.BlkioStats.IOServiceTimeRecursive)
+	assert.Equal(t, []docker.BlkioStatsEntry{{Major: 8, Minor: 0, Op: "Total", Value: 150}}, stats.BlkioStats.IOMergedRecursive)
+	assert.Equal(t, []docker.BlkioStatsEntry{{Major: 8, Minor: 0, Value: 2400}}, stats.BlkioStats.SectorsRecursive)
+
+	// pids
+	assert.Equal(t, uint64(7), stats.PidsStats.Current)
+}
+
+func TestCollectorStatsV1Systemd(t *testing.T) {
+	// GIVEN
+	// container created with the systemd cgroup driver, without memory and pids controllers, and with empty
+	// recursive blkio statistics
+	collector := NewCollector("testdata/v1")
+
+	// WHEN
+	stats, err := collector.Stats("def456")
+
+	// THEN
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1000), stats.CPUStats.CPUUsage.TotalUsage)
+	assert.Equal(t, uint64(0), stats.MemoryStats.Usage)
+	assert.Equal(t, uint64(0), stats.PidsStats.Current)
+	// throttle statistics are used instead
+	assert.Equal(t, []docker.BlkioStatsEntry{
+		{Major: 8, Minor: 16, Op: "Read", Value: 10},
+		{Major: 8, Minor: 16, Op: "Write", Value: 20},
+		{Major: 8, Minor: 16, Op: "Total", Value: 30},
+	}, stats.BlkioStats.IOServicedRecursive)
+}
+
+func TestCollectorStatsV1Unlimited(t *testing.T) {
+	// GIVEN
+	// container without memory limit, on a host with 2GiB of memory
+	collector := NewCollector("testdata/v1")
+	collector.MemTotal = 2147483648
+
+	// WHEN
+	stats, err := collector.Stats("ghi789")
+	limited, limitedErr := collector.Stats("abc123")
+
+	// THEN
+	// the limit is the host memory, as reported by docker, lower limits are kept
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2147483648), stats.MemoryStats.Limit)
+	assert.Equal(t, uint64(104857600), stats.MemoryStats.Usage)
+	assert.Nil(t, limitedErr)
+	assert.Equal(t, uint64(536870912), limited.MemoryStats.Limit)
+}
+
+func TestCollectorStatsUnknownContainer(t *testing.T) {
+	// GIVEN
+	collector := NewCollector("testdata/v1")
+
+	// WHEN
+	stats, err := collector.Stats("unknown")
+
+	// THEN
+	// like the docker API when the container does not exist anymore
+	assert.Nil(t, err)
+	assert.Nil(t, stats)
+}
+
+/*
+TestCollectorPreCPUStats simulates two samples 10 seconds apart: the PreCPUStats are the counters one second
+before the second sample, at the average rate.
+*/
+func TestCollectorPreCPUStats(t *testing.T) {
+	// GIVEN
+	collector := NewCollector("")
+	timestamp := time.Now()
+	first := getCPUStats(1000000000, []uint64{600000000, 400000000})
+	second := getCPUStats(6000000000, []uint64{3600000000, 2400000000})
+	collector.preCPUStats("abc123", timestamp, first)
+
+	// WHEN
+	pre := collector.preCPUStats("abc123", timestamp.Add(10*time.Second), second)
+
+	// THEN
+	assert.Equal(t, DefaultRoot, collector.Root)
+	assert.Equal(t, uint64(5500000000), pre.CPUUsage.TotalUsage)
+	assert.Equal(t, []uint64{3300000000, 2200000000}, pre.CPUUsage.PercpuUsage)
+}
+
+func TestCollectorPreCPUStatsReset(t *testing.T) {
+	// GIVEN
+	collector := NewCollector("")
+	timestamp := time.Now()
+	collector.preCPUStats("abc123", timestamp, getCPUStats(6000000000, []uint64{6000000000}))
+
+	// WHEN
+	// the container restarted
+	pre := collector.preCPUStats("abc123", timestamp.Add(10*time.Second), getCPUStats(1000000000, []uint64{1000000000}))
+
+	// THEN
+	// the old value is kept, so that the calculator detects the reset
+	assert.Equal(t, uint64(6000000000), pre.CPUUsage.TotalUsage)
+}
+
+func TestCollectorClean(t *testing.T) {
+	// GIVEN
+	collector := NewCollector("")
+	collector.preCPUStats("abc123", time.Now(), getCPUStats(1, nil))
+	collector.preCPUStats("def456", time.Now(), getCPUStats(1, nil))
+
+	// WHEN
+	collector.Clean(map[string]bool{"def456": true})
+
+	// THEN
+	assert.Equal(t, 1, len(collector.previous))
+	_, ok := collector.previous["def456"]
+	assert.True(t, ok)
+}
+
+func getCPUStats(total uint64, percpu []uint64) docker.CPUStats {
+	stats := docker.CPUStats{}
+	stats.CPUUsage.TotalUsage = total
+	stats.CPUUsage.PercpuUsage = percpu
+	return stats
+}
+
+func TestCollectorVersion(t *testing.T) {
+	assert.Equal(t, 1, NewCollector("testdata/v1").Version())
+	assert.Equal(t, 2, NewCollector("testdata/v2").Version())
+}
+
+func TestCollectorStatsV2(t *testing.T) {
+	// GIVEN
+	collector := NewCollector("testdata/v2")
+	collector.MemTotal = 2147483648
+
+	// WHEN
+	stats, err := collector.Stats("abc123")
+
+	// THEN
+	assert.Nil(t, err)
+	assert.NotNil(t, stats)
+
+	// cpu, times are converted from microseconds to nanoseconds
+	assert.Equal(t, uint64(5000000000), stats.CPUStats.CPUUsage.TotalUsage)
+	assert.Equal(t, uint64(3000000000), stats.CPUStats.CPUUsage.UsageInUsermode)
+	assert.Equal(t, uint64(1500000000), stats.CPUStats.CPUUsage.UsageInKernelmode)
+	assert.Nil(t, stats.CPUStats.CPUUsage.PercpuUsage)
+
+	// memory, the "max" limit is the host memory as with docker, v2 statistics are converted to their v1 names
+	assert.Equal(t, uint64(104857600), stats.MemoryStats.Usage)
+	assert.Equal(t, uint64(0), stats.MemoryStats.MaxUsage)
+	assert.Equal(t, uint64(2147483648), stats.MemoryStats.Limit)
+	assert.Equal(t, uint64(2), stats.MemoryStats.Failcnt)
+	assert.Equal(t, uint64(52428800), stats.MemoryStats.Stats.Rss)
+	assert.Equal(t, uint64(52428800), stats.MemoryStats.Stats.TotalRss)
+	assert.Equal(t, uint64(1048576), stats.MemoryStats.Stats.TotalCache)
+	assert.Equal(t, uint64(3), stats.MemoryStats.Stats.TotalPgmafault)
+
+	// io
+	assert.Equal(t, []docker.BlkioStatsEntry{
+		{Major: 8, Minor: 0, Op: "Read", Value: 409600},
+		{Major: 8, Minor: 0, Op: "Write", Value: 819200},
+		{Major: 8, Minor: 0, Op: "Total", Value: 1228800},
+		{Major: 8, Minor: 16, Op: "Read", Value: 4096},
+		{Major: 8, Minor: 16, Op: "Write", Value: 0},
+		{Major: 8, Minor: 16, Op: "Total", Value: 4096},
+	}, stats.BlkioStats.IOServiceBytesRecursive)
+	assert.Equal(t, []docker.BlkioStatsEntry{
+		{Major: 8, Minor: 0, Op: "Read", Value: 100},
+		{Major: 8, Minor: 0, Op: "Write", Value: 200},
+		{Major: 8, Minor: 0, Op: "Total", Value: 300},
+		{Major: 8, Minor: 16, Op: "Read", Value: 1},
+		{Major: 8, Minor: 16, Op: "Write", Value: 0},
+		{Major: 8, Minor: 16, Op: "Total", Value: 1},
+	}, stats.BlkioStats.IOServicedRecursive)
+
+	// pids
+	assert.Equal(t, uint64(7), stats.PidsStats.Current)
+}
+
+func TestCollectorStatsV2UnknownContainer(t *testing.T) {
+	// GIVEN
+	collector := NewCollector("testdata/v2")
+
+	// WHEN
+	stats, err := collector.Stats("unknown")
+
+	// THEN
+	assert.Nil(t, err)
+	assert.Nil(t, stats)
+}
+
+func TestCollectorMemoryEvents(t *testing.T) {
+	// only available with cgroup v2
+	assert.Equal(t, map[string]uint64{"low": 0, "high": 4, "max": 2, "oom": 1, "oom_kill": 1}, NewCollector("testdata/v2").MemoryEvents("abc123"))
+	assert.Nil(t, NewCollector("testdata/v2").MemoryEvents("unknown"))
+	assert.Nil(t, NewCollector("testdata/v1").MemoryEvents("abc123"))
+}
+
+func TestCollectorPids(t *testing.T) {
+	pids, err := NewCollector("testdata/v1").Pids("abc123")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1234, 5678}, pids)
+
+	pids, err = NewCollector("testdata/v2").Pids("abc123")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1234}, pids)
+
+	// unknown container
+	pids, err = NewCollector("testdata/v1").Pids("unknown")
+	assert.Nil(t, err)
+	assert.Nil(t, pids)
+}
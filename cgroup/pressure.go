@@ -0,0 +1,94 @@
+package cgroup
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PressureStats is one line of a pressure file: the share of time (in percents) some or all tasks were stalled
+// on the resource over the last 10, 60 and 300 seconds, and the cumulated stall time in microseconds.
+type PressureStats struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  uint64
+}
+
+// Pressure contains the pressure stall information (PSI) of a container, for the "some" and "full" lines
+// of each resource.
+type Pressure struct {
+	Time   time.Time
+	CPU    map[string]PressureStats
+	Memory map[string]PressureStats
+	IO     map[string]PressureStats
+}
+
+/*
+Pressure returns the pressure stall information of the given container, read from the cpu.pressure,
+memory.pressure and io.pressure files. They are only available with cgroup v2 (and a kernel with PSI enabled):
+nil is returned when they are not available.
+*/
+func (c *Collector) Pressure(id string) (*Pressure, error) {
+	if c.Version() != 2 {
+		return nil, nil
+	}
+	path := c.v2Path(id)
+	if path == "" {
+		return nil, nil
+	}
+
+	pressure := &Pressure{Time: time.Now()}
+	files := map[string]*map[string]PressureStats{
+		"cpu.pressure":    &pressure.CPU,
+		"memory.pressure": &pressure.Memory,
+		"io.pressure":     &pressure.IO,
+	}
+	for file, stats := range files {
+		var err error
+		if *stats, err = readPressure(filepath.Join(path, file)); err != nil {
+			return nil, optional(err)
+		}
+	}
+	return pressure, nil
+}
+
+/*
+readPressure parses a pressure file:
+
+	some avg10=0.12 avg60=0.05 avg300=0.01 total=123456
+	full avg10=0.00 avg60=0.00 avg300=0.00 total=6789
+*/
+func readPressure(path string) (map[string]PressureStats, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	output := map[string]PressureStats{}
+	for _, fields := range lines {
+		stats := PressureStats{}
+		for _, field := range fields[1:] {
+			keyValue := strings.SplitN(field, "=", 2)
+			if len(keyValue) != 2 {
+				continue
+			}
+			switch keyValue[0] {
+			case "avg10":
+				stats.Avg10, err = strconv.ParseFloat(keyValue[1], 64)
+			case "avg60":
+				stats.Avg60, err = strconv.ParseFloat(keyValue[1], 64)
+			case "avg300":
+				stats.Avg300, err = strconv.ParseFloat(keyValue[1], 64)
+			case "total":
+				stats.Total, err = strconv.ParseUint(keyValue[1], 10, 64)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		output[fields[0]] = stats
+	}
+	return output, nil
+}
@@ -0,0 +1,37 @@
+package cgroup
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCollectorPressure(t *testing.T) {
+	// GIVEN
+	collector := NewCollector("testdata/v2")
+
+	// WHEN
+	pressure, err := collector.Pressure("abc123")
+
+	// THEN
+	assert.Nil(t, err)
+	assert.NotNil(t, pressure)
+	assert.False(t, pressure.Time.IsZero())
+	assert.Equal(t, map[string]PressureStats{
+		"some": {Avg10: 1.5, Avg60: 0.8, Avg300: 0.2, Total: 2500000},
+		"full": {Total: 0},
+	}, pressure.CPU)
+	assert.Equal(t, PressureStats{Avg10: 0, Avg60: 0.05, Avg300: 0.02, Total: 150000}, pressure.Memory["full"])
+	assert.Equal(t, PressureStats{Avg10: 12.3, Avg60: 8.2, Avg300: 3.1, Total: 9000000}, pressure.IO["some"])
+}
+
+func TestCollectorPressureUnavailable(t *testing.T) {
+	// cgroup v1
+	pressure, err := NewCollector("testdata/v1").Pressure("abc123")
+	assert.Nil(t, err)
+	assert.Nil(t, pressure)
+
+	// unknown container
+	pressure, err = NewCollector("testdata/v2").Pressure("unknown")
+	assert.Nil(t, err)
+	assert.Nil(t, pressure)
+}
@@ -0,0 +1,220 @@
+package cgroup
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// userHz is the unit of cpuacct.stat times (USER_HZ, 100 on all supported architectures)
+const userHz = 100
+
+// v1Paths contains the container directories in each cgroup v1 hierarchy
+type v1Paths struct {
+	cpuacct string
+	memory  string
+	blkio   string
+	pids    string
+}
+
+// v1Paths returns the container directories, or nil when the container cannot be found
+func (c *Collector) v1Paths(id string) *v1Paths {
+	for _, dir := range containerDirs(id) {
+		if exists(filepath.Join(c.Root, "cpuacct", dir)) {
+			return &v1Paths{
+				cpuacct: filepath.Join(c.Root, "cpuacct", dir),
+				memory:  filepath.Join(c.Root, "memory", dir),
+				blkio:   filepath.Join(c.Root, "blkio", dir),
+				pids:    filepath.Join(c.Root, "pids", dir),
+			}
+		}
+	}
+	return nil
+}
+
+func readV1(paths *v1Paths, stats *docker.Stats) error {
+	if err := readV1Cpu(paths.cpuacct, stats); err != nil {
+		return err
+	}
+	if err := readV1Memory(paths.memory, stats); err != nil {
+		return err
+	}
+	if err := readV1Blkio(paths.blkio, stats); err != nil {
+		return err
+	}
+	return readV1Pids(paths.pids, stats)
+}
+
+func readV1Cpu(path string, stats *docker.Stats) error {
+	usage := &stats.CPUStats.CPUUsage
+
+	var err error
+	if usage.TotalUsage, err = readUint(filepath.Join(path, "cpuacct.usage")); err != nil {
+		return err
+	}
+
+	lines, err := readLines(filepath.Join(path, "cpuacct.usage_percpu"))
+	if optional(err) != nil {
+		return err
+	}
+	for _, fields := range lines {
+		for _, field := range fields {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return err
+			}
+			usage.PercpuUsage = append(usage.PercpuUsage, value)
+		}
+	}
+
+	// user and system times are in USER_HZ, the docker API gives them in nanoseconds
+	times, err := readKeyValues(filepath.Join(path, "cpuacct.stat"))
+	if optional(err) != nil {
+		return err
+	}
+	usage.UsageInUsermode = times["user"] * uint64(time.Second) / userHz
+	usage.UsageInKernelmode = times["system"] * uint64(time.Second) / userHz
+	return nil
+}
+
+func readV1Memory(path string, stats *docker.Stats) error {
+	memory := &stats.MemoryStats
+	files := map[string]*uint64{
+		"memory.usage_in_bytes":     &memory.Usage,
+		"memory.max_usage_in_bytes": &memory.MaxUsage,
+		"memory.failcnt":            &memory.Failcnt,
+		"memory.limit_in_bytes":     &memory.Limit,
+	}
+	for file, value := range files {
+		var err error
+		if *value, err = readUint(filepath.Join(path, file)); optional(err) != nil {
+			return err
+		}
+	}
+
+	values, err := readKeyValues(filepath.Join(path, "memory.stat"))
+	if optional(err) != nil {
+		return err
+	}
+	fillMemoryStats(stats, values)
+	return nil
+}
+
+// fillMemoryStats copies memory.stat values into the docker memory stats
+func fillMemoryStats(stats *docker.Stats, values map[string]uint64) {
+	s := &stats.MemoryStats.Stats
+	fields := map[string]*uint64{
+		"cache":                     &s.Cache,
+		"rss":                       &s.Rss,
+		"rss_huge":                  &s.RssHuge,
+		"mapped_file":               &s.MappedFile,
+		"writeback":                 &s.Writeback,
+		"swap":                      &s.Swap,
+		"pgpgin":                    &s.Pgpgin,
+		"pgpgout":                   &s.Pgpgout,
+		"pgfault":                   &s.Pgfault,
+		"pgmajfault":                &s.Pgmajfault,
+		"inactive_anon":             &s.InactiveAnon,
+		"active_anon":               &s.ActiveAnon,
+		"inactive_file":             &s.InactiveFile,
+		"active_file":               &s.ActiveFile,
+		"unevictable":               &s.Unevictable,
+		"hierarchical_memory_limit": &s.HierarchicalMemoryLimit,
+		"hierarchical_memsw_limit":  &s.HierarchicalMemswLimit,
+		"total_cache":               &s.TotalCache,
+		"total_rss":                 &s.TotalRss,
+		"total_rss_huge":            &s.TotalRssHuge,
+		"total_mapped_file":         &s.TotalMappedFile,
+		"total_writeback":           &s.TotalWriteback,
+		"total_pgpgin":              &s.TotalPgpgin,
+		"total_pgpgout":             &s.TotalPgpgout,
+		"total_pgfault":             &s.TotalPgfault,
+		"total_pgmajfault":          &s.TotalPgmafault,
+		"total_inactive_anon":       &s.TotalInactiveAnon,
+		"total_active_anon":         &s.TotalActiveAnon,
+		"total_inactive_file":       &s.TotalInactiveFile,
+		"total_active_file":         &s.TotalActiveFile,
+		"total_unevictable":         &s.TotalUnevictable,
+	}
+	for key, value := range values {
+		if field, ok := fields[key]; ok {
+			*field = value
+		}
+	}
+}
+
+func readV1Blkio(path string, stats *docker.Stats) error {
+	blkio := &stats.BlkioStats
+	// the throttle files are used when the recursive ones are empty, which happens when the CFQ
+	// scheduler is not used
+	files := []struct {
+		entries  *[]docker.BlkioStatsEntry
+		name     string
+		fallback string
+	}{
+		{&blkio.IOServiceBytesRecursive, "blkio.io_service_bytes_recursive", "blkio.throttle.io_service_bytes"},
+		{&blkio.IOServicedRecursive, "blkio.io_serviced_recursive", "blkio.throttle.io_serviced"},
+		{&blkio.IOQueueRecursive, "blkio.io_queued_recursive", ""},
+		{&blkio.IOServiceTimeRecursive, "blkio.io_service_time_recursive", ""},
+		{&blkio.IOWaitTimeRecursive, "blkio.io_wait_time_recursive", ""},
+		{&blkio.IOMergedRecursive, "blkio.io_merged_recursive", ""},
+		{&blkio.IOTimeRecursive, "blkio.time_recursive", ""},
+		{&blkio.SectorsRecursive, "blkio.sectors_recursive", ""},
+	}
+	for _, file := range files {
+		entries, err := readBlkioEntries(filepath.Join(path, file.name))
+		if optional(err) != nil {
+			return err
+		}
+		if len(entries) == 0 && file.fallback != "" {
+			if entries, err = readBlkioEntries(filepath.Join(path, file.fallback)); optional(err) != nil {
+				return err
+			}
+		}
+		*file.entries = entries
+	}
+	return nil
+}
+
+// readBlkioEntries parses "major:minor [operation] value" lines, the cgroup total line is ignored
+func readBlkioEntries(path string) ([]docker.BlkioStatsEntry, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []docker.BlkioStatsEntry{}
+	for _, fields := range lines {
+		device := strings.Split(fields[0], ":")
+		if len(device) != 2 || len(fields) < 2 {
+			continue
+		}
+		entry := docker.BlkioStatsEntry{}
+		if entry.Major, err = strconv.ParseUint(device[0], 10, 64); err != nil {
+			return nil, err
+		}
+		if entry.Minor, err = strconv.ParseUint(device[1], 10, 64); err != nil {
+			return nil, err
+		}
+		if len(fields) > 2 {
+			entry.Op = fields[1]
+		}
+		if entry.Value, err = strconv.ParseUint(fields[len(fields)-1], 10, 64); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func readV1Pids(path string, stats *docker.Stats) error {
+	current, err := readUint(filepath.Join(path, "pids.current"))
+	if optional(err) != nil {
+		return err
+	}
+	stats.PidsStats.Current = current
+	return nil
+}
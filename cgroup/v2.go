@@ -0,0 +1,155 @@
+package cgroup
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// v2Path returns the container directory in the unified hierarchy, or "" when the container cannot be found
+func (c *Collector) v2Path(id string) string {
+	for _, dir := range containerDirs(id) {
+		if path := filepath.Join(c.Root, dir); exists(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+func readV2(path string, stats *docker.Stats) error {
+	if err := readV2Cpu(path, stats); err != nil {
+		return err
+	}
+	if err := readV2Memory(path, stats); err != nil {
+		return err
+	}
+	if err := readV2Io(path, stats); err != nil {
+		return err
+	}
+	return readV1Pids(path, stats)
+}
+
+// readV2Cpu reads cpu.stat, whose times are in microseconds. Per cpu usage is not available with cgroup v2.
+func readV2Cpu(path string, stats *docker.Stats) error {
+	values, err := readKeyValues(filepath.Join(path, "cpu.stat"))
+	if err != nil {
+		return err
+	}
+	usage := &stats.CPUStats.CPUUsage
+	usage.TotalUsage = values["usage_usec"] * uint64(time.Microsecond)
+	usage.UsageInUsermode = values["user_usec"] * uint64(time.Microsecond)
+	usage.UsageInKernelmode = values["system_usec"] * uint64(time.Microsecond)
+	return nil
+}
+
+func readV2Memory(path string, stats *docker.Stats) error {
+	memory := &stats.MemoryStats
+	files := map[string]*uint64{
+		"memory.current": &memory.Usage,
+		"memory.peak":    &memory.MaxUsage,
+		"memory.max":     &memory.Limit,
+	}
+	for file, value := range files {
+		var err error
+		if *value, err = readUint(filepath.Join(path, file)); optional(err) != nil {
+			return err
+		}
+	}
+
+	// the closest to the v1 failcnt is the number of times the usage reached the limit
+	events, err := readKeyValues(filepath.Join(path, "memory.events"))
+	if optional(err) != nil {
+		return err
+	}
+	memory.Failcnt = events["max"]
+
+	values, err := readKeyValues(filepath.Join(path, "memory.stat"))
+	if optional(err) != nil {
+		return err
+	}
+	swap, err := readUint(filepath.Join(path, "memory.swap.current"))
+	if optional(err) != nil {
+		return err
+	}
+
+	// v2 statistics are always hierarchical, they are converted to their v1 names, with and without total_ prefix
+	v1Values := map[string]uint64{"swap": swap}
+	names := map[string]string{
+		"anon":           "rss",
+		"anon_thp":       "rss_huge",
+		"file":           "cache",
+		"file_mapped":    "mapped_file",
+		"file_writeback": "writeback",
+		"pgfault":        "pgfault",
+		"pgmajfault":     "pgmajfault",
+		"inactive_anon":  "inactive_anon",
+		"active_anon":    "active_anon",
+		"inactive_file":  "inactive_file",
+		"active_file":    "active_file",
+		"unevictable":    "unevictable",
+	}
+	for v2Name, v1Name := range names {
+		if value, ok := values[v2Name]; ok {
+			v1Values[v1Name] = value
+			v1Values["total_"+v1Name] = value
+		}
+	}
+	fillMemoryStats(stats, v1Values)
+	return nil
+}
+
+/*
+readV2Io reads io.stat, made of one line per device with the cumulated bytes and operations:
+
+	8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+
+They are converted to the v1 service bytes and serviced entries.
+*/
+func readV2Io(path string, stats *docker.Stats) error {
+	lines, err := readLines(filepath.Join(path, "io.stat"))
+	if optional(err) != nil {
+		return err
+	}
+
+	blkio := &stats.BlkioStats
+	for _, fields := range lines {
+		device := strings.Split(fields[0], ":")
+		if len(device) != 2 {
+			continue
+		}
+		major, err := strconv.ParseUint(device[0], 10, 64)
+		if err != nil {
+			return err
+		}
+		minor, err := strconv.ParseUint(device[1], 10, 64)
+		if err != nil {
+			return err
+		}
+
+		values := map[string]uint64{}
+		for _, field := range fields[1:] {
+			keyValue := strings.SplitN(field, "=", 2)
+			if len(keyValue) != 2 {
+				continue
+			}
+			if values[keyValue[0]], err = strconv.ParseUint(keyValue[1], 10, 64); err != nil {
+				return err
+			}
+		}
+
+		blkio.IOServiceBytesRecursive = append(blkio.IOServiceBytesRecursive,
+			docker.BlkioStatsEntry{Major: major, Minor: minor, Op: "Read", Value: values["rbytes"]},
+			docker.BlkioStatsEntry{Major: major, Minor: minor, Op: "Write", Value: values["wbytes"]},
+			docker.BlkioStatsEntry{Major: major, Minor: minor, Op: "Total", Value: values["rbytes"] + values["wbytes"]},
+		)
+		blkio.IOServicedRecursive = append(blkio.IOServicedRecursive,
+			docker.BlkioStatsEntry{Major: major, Minor: minor, Op: "Read", Value: values["rios"]},
+			docker.BlkioStatsEntry{Major: major, Minor: minor, Op: "Write", Value: values["wios"]},
+			docker.BlkioStatsEntry{Major: major, Minor: minor, Op: "Total", Value: values["rios"] + values["wios"]},
+		)
+	}
+	return nil
+}
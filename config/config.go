@@ -20,11 +20,79 @@ type StatsConfig struct {
 	Memory    *bool `config:"memory"`
 	Blkio     *bool `config:"blkio"`
 	Cpu       *bool `config:"cpu"`
+	Pids      *bool `config:"pids"`
+	Pressure  *bool `config:"pressure"`
+	Tcp       *bool `config:"tcp"`
+	Listen    *bool `config:"listen"`
+	Fd        *bool `config:"fd"`
+	Oom       *bool `config:"oom"`
+}
+
+type AggregateConfig struct {
+	Enabled *bool    `config:"enabled"`
+	GroupBy []string `config:"group_by"`
+	Top     *int     `config:"top"`
+}
+
+type RollupConfig struct {
+	Window     *int64   `config:"window"`
+	Percentile *float64 `config:"percentile"`
+}
+
+type ChangesConfig struct {
+	Enabled   *bool  `config:"enabled"`
+	Heartbeat *int64 `config:"heartbeat"`
+}
+
+type PrometheusConfig struct {
+	Enabled *bool    `config:"enabled"`
+	Address *string  `config:"address"`
+	Labels  []string `config:"labels"`
+}
+
+type SizeConfig struct {
+	Enabled *bool  `config:"enabled"`
+	Period  *int64 `config:"period"`
+}
+
+type CrashloopConfig struct {
+	Enabled  *bool  `config:"enabled"`
+	Restarts *int   `config:"restarts"`
+	Window   *int64 `config:"window"`
+}
+
+type RuleConfig struct {
+	Name    *string  `config:"name"`
+	Expr    *string  `config:"expr"`
+	Resolve *float64 `config:"resolve"`
+}
+
+type CgroupConfig struct {
+	Root *string `config:"root"`
+}
+
+type ProcConfig struct {
+	Root *string `config:"root"`
+}
+
+type SysConfig struct {
+	Root *string `config:"root"`
 }
 
 type DockbeatConfig struct {
-	Period *int64      `config:"period"`
-	Socket *string     `config:"socket"`
-	Tls    TlsConfig   `config:"tls"`
-	Stats  StatsConfig `config:"stats"`
+	Period     *int64           `config:"period"`
+	Socket     *string          `config:"socket"`
+	Tls        TlsConfig        `config:"tls"`
+	Stats      StatsConfig      `config:"stats"`
+	Aggregate  AggregateConfig  `config:"aggregate"`
+	Rollup     RollupConfig     `config:"rollup"`
+	Changes    ChangesConfig    `config:"changes"`
+	Rules      []RuleConfig     `config:"rules"`
+	Crashloop  CrashloopConfig  `config:"crashloop"`
+	Size       SizeConfig       `config:"size"`
+	Prometheus PrometheusConfig `config:"prometheus"`
+	Collector  *string          `config:"collector"`
+	Cgroup     CgroupConfig     `config:"cgroup"`
+	Proc       ProcConfig       `config:"proc"`
+	Sys        SysConfig        `config:"sys"`
 }
@@ -0,0 +1,258 @@
+package event
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/fsouza/go-dockerclient"
+)
+
+const (
+	AggregateByImage          = "image"
+	AggregateByComposeService = "compose_service"
+	// AggregateByLabelPrefix is followed by the label key, e.g. "label:team"
+	AggregateByLabelPrefix = "label:"
+
+	composeServiceLabel = "com.docker.compose.service"
+)
+
+// aggregatedMetric describes a metric summed in aggregate events: the section and field it is read from
+// in container events, and whether it is used to rank the top consumers.
+type aggregatedMetric struct {
+	section string
+	field   string
+	top     bool
+}
+
+var aggregatedMetrics = []aggregatedMetric{
+	{"cpu", "totalUsage", true},
+	{"memory", "usage", true},
+	{"memory", "totalRss", false},
+	{"net", "rxBytes_ps", true},
+	{"net", "txBytes_ps", true},
+	{"net", "rxPackets_ps", false},
+	{"net", "txPackets_ps", false},
+	{"blkio", "readBytes_ps", true},
+	{"blkio", "writeBytes_ps", true},
+	{"blkio", "read_ps", false},
+	{"blkio", "write_ps", false},
+	{"pids", "current", false},
+}
+
+// containerUsage holds the metrics of one container for the current tick, indexed by "section.field"
+type containerUsage struct {
+	container *docker.APIContainers
+	values    map[string]float64
+	// sampled is false when no event was generated for the container, e.g. when its stats request failed
+	sampled bool
+}
+
+type aggregateGroup struct {
+	scope string
+	key   string
+	value string
+	usage []*containerUsage
+}
+
+/*
+GetAggregateEvents generates aggregate events from all the events generated for the containers during one tick:
+  - one event for the whole host
+  - one event per group, for each grouping key (image, compose_service or label:<key>)
+
+Each aggregate event contains the sum of the main metrics, the number of containers and the top consumers. Only the
+containers which generated events during the tick are counted and ranked, the others are counted as failed.
+*/
+func (d *EventGenerator) GetAggregateEvents(timestamp time.Time, containers []docker.APIContainers, events []common.MapStr, groupBy []string, top int) []common.MapStr {
+	logp.Debug("generator", "Generate aggregate events for %v containers", len(containers))
+	usages := d.buildContainerUsages(containers, events)
+
+	groups := []*aggregateGroup{{scope: "host", usage: usages}}
+	for _, key := range groupBy {
+		groups = append(groups, d.groupUsages(key, usages)...)
+	}
+
+	output := []common.MapStr{}
+	for _, group := range groups {
+		output = append(output, d.buildAggregateEvent(timestamp, group, top))
+	}
+	return output
+}
+
+func (d *EventGenerator) buildContainerUsages(containers []docker.APIContainers, events []common.MapStr) []*containerUsage {
+	usages := make([]*containerUsage, 0, len(containers))
+	byID := map[string]*containerUsage{}
+	for i := range containers {
+		usage := &containerUsage{container: &containers[i], values: map[string]float64{}}
+		usages = append(usages, usage)
+		byID[containers[i].ID] = usage
+	}
+
+	for _, event := range events {
+		id, _ := event["containerID"].(string)
+		usage, ok := byID[id]
+		if !ok {
+			continue
+		}
+		usage.sampled = true
+		for _, metric := range aggregatedMetrics {
+			section, ok := event[metric.section].(common.MapStr)
+			if !ok {
+				continue
+			}
+			// net events are generated per interface, so values are summed
+			if value, ok := toFloat(section[metric.field]); ok {
+				usage.values[metric.section+"."+metric.field] += value
+			}
+		}
+	}
+	return usages
+}
+
+func (d *EventGenerator) groupUsages(key string, usages []*containerUsage) []*aggregateGroup {
+	scope := key
+	label := ""
+	switch {
+	case key == AggregateByImage:
+	case key == AggregateByComposeService:
+		label = composeServiceLabel
+	case strings.HasPrefix(key, AggregateByLabelPrefix):
+		scope = "label"
+		label = strings.TrimPrefix(key, AggregateByLabelPrefix)
+	default:
+		logp.Warn("Unknown aggregation key %v", key)
+		return nil
+	}
+
+	groups := map[string]*aggregateGroup{}
+	values := []string{}
+	for _, usage := range usages {
+		var value string
+		if label == "" {
+			value = usage.container.Image
+		} else if labelValue, ok := usage.container.Labels[label]; ok {
+			value = labelValue
+		} else {
+			// containers without the label are not part of any group
+			continue
+		}
+
+		group, exists := groups[value]
+		if !exists {
+			group = &aggregateGroup{scope: scope, key: label, value: value}
+			groups[value] = group
+			values = append(values, value)
+		}
+		group.usage = append(group.usage, usage)
+	}
+
+	sort.Strings(values)
+	output := make([]*aggregateGroup, 0, len(values))
+	for _, value := range values {
+		output = append(output, groups[value])
+	}
+	return output
+}
+
+func (d *EventGenerator) buildAggregateEvent(timestamp time.Time, group *aggregateGroup, top int) common.MapStr {
+	sampled := make([]*containerUsage, 0, len(group.usage))
+	for _, usage := range group.usage {
+		if usage.sampled {
+			sampled = append(sampled, usage)
+		}
+	}
+	aggregate := common.MapStr{
+		"scope":      group.scope,
+		"containers": len(sampled),
+		"failed":     len(group.usage) - len(sampled),
+	}
+	if group.scope != "host" {
+		aggregate["value"] = group.value
+	}
+	if group.key != "" {
+		aggregate["key"] = strings.Replace(group.key, ".", "_", -1)
+	}
+
+	topConsumers := common.MapStr{}
+	for _, metric := range aggregatedMetrics {
+		name := metric.section + "." + metric.field
+		sum := float64(0)
+		for _, usage := range sampled {
+			sum += usage.values[name]
+		}
+
+		section, ok := aggregate[metric.section].(common.MapStr)
+		if !ok {
+			section = common.MapStr{}
+			aggregate[metric.section] = section
+		}
+		section[metric.field] = sum
+
+		if metric.top && top > 0 {
+			topConsumers[strings.Replace(name, ".", "_", -1)] = d.topConsumers(name, sampled, top)
+		}
+	}
+	aggregate["top"] = topConsumers
+
+	return common.MapStr{
+		"@timestamp":   common.Time(timestamp),
+		"type":         "aggregate",
+		"dockerSocket": d.Socket,
+		"aggregate":    aggregate,
+	}
+}
+
+// topConsumers returns the containers with the highest values for the given metric, highest first
+func (d *EventGenerator) topConsumers(name string, usages []*containerUsage, top int) []common.MapStr {
+	sorted := usagesByMetric{name: name, usages: make([]*containerUsage, len(usages))}
+	copy(sorted.usages, usages)
+	sort.Stable(sort.Reverse(sorted))
+	if len(sorted.usages) > top {
+		sorted.usages = sorted.usages[:top]
+	}
+
+	output := make([]common.MapStr, 0, len(sorted.usages))
+	for _, usage := range sorted.usages {
+		output = append(output, common.MapStr{
+			"containerID":   usage.container.ID,
+			"containerName": d.extractContainerName(usage.container.Names),
+			"value":         usage.values[name],
+		})
+	}
+	return output
+}
+
+// usagesByMetric sorts container usages according to one metric
+type usagesByMetric struct {
+	name   string
+	usages []*containerUsage
+}
+
+func (u usagesByMetric) Len() int      { return len(u.usages) }
+func (u usagesByMetric) Swap(i, j int) { u.usages[i], u.usages[j] = u.usages[j], u.usages[i] }
+func (u usagesByMetric) Less(i, j int) bool {
+	return u.usages[i].values[u.name] < u.usages[j].values[u.name]
+}
+
+// toFloat converts numeric event values to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	}
+	return 0, false
+}
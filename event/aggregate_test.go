@@ -0,0 +1,108 @@
+package event
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+/*
+TestEventGeneratorGetAggregateEvents simulates the end of a tick with three containers:
+  - "web1" and "web2" run the "nginx" image and belong to the "web" compose service
+  - "db" runs the "postgres" image and has no compose label
+
+This test checks that a host aggregate, image aggregates and compose service aggregates are generated.
+*/
+func TestEventGeneratorGetAggregateEvents(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	timestamp := time.Now()
+	containers := []docker.APIContainers{
+		{ID: "web1", Image: "nginx", Names: []string{"/web1"}, Labels: map[string]string{"com.docker.compose.service": "web"}},
+		{ID: "web2", Image: "nginx", Names: []string{"/web2"}, Labels: map[string]string{"com.docker.compose.service": "web"}},
+		{ID: "db", Image: "postgres", Names: []string{"/db"}},
+	}
+	events := []common.MapStr{
+		{"type": "cpu", "containerID": "web1", "cpu": common.MapStr{"totalUsage": 0.5}},
+		{"type": "cpu", "containerID": "web2", "cpu": common.MapStr{"totalUsage": 0.25}},
+		{"type": "cpu", "containerID": "db", "cpu": common.MapStr{"totalUsage": 1.0}},
+		{"type": "memory", "containerID": "web1", "memory": common.MapStr{"usage": uint64(100)}},
+		{"type": "memory", "containerID": "web2", "memory": common.MapStr{"usage": uint64(200)}},
+		{"type": "memory", "containerID": "db", "memory": common.MapStr{"usage": uint64(1000)}},
+		// two networks for web1
+		{"type": "net", "containerID": "web1", "net": common.MapStr{"name": "eth0", "rxBytes_ps": float64(10)}},
+		{"type": "net", "containerID": "web1", "net": common.MapStr{"name": "eth1", "rxBytes_ps": float64(5)}},
+		// events from an unknown container are ignored
+		{"type": "cpu", "containerID": "unknown", "cpu": common.MapStr{"totalUsage": 4.0}},
+	}
+	// the stats request of "cache" failed, it has no event
+	containers = append(containers, docker.APIContainers{ID: "cache", Image: "redis", Names: []string{"/cache"}})
+	var eventGenerator = EventGenerator{Socket: &socket, Period: time.Second}
+
+	// WHEN
+	aggregates := eventGenerator.GetAggregateEvents(timestamp, containers, events, []string{"image", "compose_service"}, 1)
+
+	// THEN
+	// host + nginx + postgres + redis + web
+	assert.Equal(t, 5, len(aggregates))
+
+	host := aggregates[0]["aggregate"].(common.MapStr)
+	assert.Equal(t, "aggregate", aggregates[0]["type"])
+	assert.Equal(t, common.Time(timestamp), aggregates[0]["@timestamp"])
+	assert.Equal(t, "host", host["scope"])
+	assert.Equal(t, 3, host["containers"])
+	assert.Equal(t, 1, host["failed"])
+	assert.Equal(t, 1.75, host["cpu"].(common.MapStr)["totalUsage"])
+	assert.Equal(t, float64(1300), host["memory"].(common.MapStr)["usage"])
+	assert.Equal(t, float64(15), host["net"].(common.MapStr)["rxBytes_ps"])
+	assert.Equal(t, []common.MapStr{{"containerID": "db", "containerName": "db", "value": 1.0}}, host["top"].(common.MapStr)["cpu_totalUsage"])
+	assert.Equal(t, []common.MapStr{{"containerID": "web1", "containerName": "web1", "value": float64(15)}}, host["top"].(common.MapStr)["net_rxBytes_ps"])
+
+	nginx := aggregates[1]["aggregate"].(common.MapStr)
+	assert.Equal(t, "image", nginx["scope"])
+	assert.Equal(t, "nginx", nginx["value"])
+	assert.Equal(t, 2, nginx["containers"])
+	assert.Equal(t, 0.75, nginx["cpu"].(common.MapStr)["totalUsage"])
+	assert.Equal(t, []common.MapStr{{"containerID": "web2", "containerName": "web2", "value": float64(200)}}, nginx["top"].(common.MapStr)["memory_usage"])
+
+	postgres := aggregates[2]["aggregate"].(common.MapStr)
+	assert.Equal(t, "postgres", postgres["value"])
+	assert.Equal(t, 1, postgres["containers"])
+	assert.Equal(t, 0, postgres["failed"])
+
+	redis := aggregates[3]["aggregate"].(common.MapStr)
+	assert.Equal(t, 0, redis["containers"])
+	assert.Equal(t, 1, redis["failed"])
+	assert.Equal(t, []common.MapStr{}, redis["top"].(common.MapStr)["cpu_totalUsage"])
+
+	web := aggregates[4]["aggregate"].(common.MapStr)
+	assert.Equal(t, "compose_service", web["scope"])
+	assert.Equal(t, "com_docker_compose_service", web["key"])
+	assert.Equal(t, "web", web["value"])
+	assert.Equal(t, 2, web["containers"])
+	assert.Equal(t, float64(300), web["memory"].(common.MapStr)["usage"])
+}
+
+func TestEventGeneratorGetAggregateEventsByLabel(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	containers := []docker.APIContainers{
+		{ID: "c1", Image: "app", Names: []string{"/c1"}, Labels: map[string]string{"team": "core"}},
+		{ID: "c2", Image: "app", Names: []string{"/c2"}, Labels: map[string]string{"team": "front"}},
+		{ID: "c3", Image: "app", Names: []string{"/c3"}},
+	}
+	var eventGenerator = EventGenerator{Socket: &socket, Period: time.Second}
+
+	// WHEN
+	aggregates := eventGenerator.GetAggregateEvents(time.Now(), containers, []common.MapStr{}, []string{"label:team", "unknown"}, 3)
+
+	// THEN
+	// host + core + front, the container without label and the unknown key are ignored
+	assert.Equal(t, 3, len(aggregates))
+	assert.Equal(t, "label", aggregates[1]["aggregate"].(common.MapStr)["scope"])
+	assert.Equal(t, "team", aggregates[1]["aggregate"].(common.MapStr)["key"])
+	assert.Equal(t, "core", aggregates[1]["aggregate"].(common.MapStr)["value"])
+	assert.Equal(t, "front", aggregates[2]["aggregate"].(common.MapStr)["value"])
+}
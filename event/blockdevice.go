@@ -0,0 +1,49 @@
+package event
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultSysRoot is the mount point of the sysfs filesystem on the host
+const DefaultSysRoot = "/sys"
+
+// EGBlockDevices resolves "major:minor" device numbers to kernel device names (sda, dm-0, ...).
+// Resolved names are cached as devices do not change while they are in use.
+type EGBlockDevices struct {
+	sync.RWMutex
+	// Root is the mount point of the host sysfs filesystem, DefaultSysRoot when empty
+	Root string
+	M    map[string]string
+}
+
+// Name returns the device name of the given "major:minor" id, or the id itself
+// when it cannot be found in sysfs.
+func (b *EGBlockDevices) Name(id string) string {
+	b.RLock()
+	name, ok := b.M[id]
+	b.RUnlock()
+	if ok {
+		return name
+	}
+
+	root := b.Root
+	if root == "" {
+		root = DefaultSysRoot
+	}
+
+	// /sys/dev/block/<major>:<minor> is a symlink to the device directory, named after the device
+	name = id
+	if target, err := os.Readlink(filepath.Join(root, "dev", "block", id)); err == nil {
+		name = filepath.Base(target)
+	}
+
+	b.Lock()
+	if b.M == nil {
+		b.M = map[string]string{}
+	}
+	b.M[id] = name
+	b.Unlock()
+	return name
+}
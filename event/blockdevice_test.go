@@ -0,0 +1,44 @@
+package event
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBlockDevicesName(t *testing.T) {
+	// GIVEN
+	// a fake sysfs root with a /dev/block link for device 8:0
+	root, err := ioutil.TempDir("", "dockbeat-block")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "dev", "block"), 0755))
+	assert.Nil(t, os.Symlink("../../devices/pci0000:00/0000:00:01.1/ata1/host0/target0:0:0/0:0:0:0/block/sda", filepath.Join(root, "dev", "block", "8:0")))
+
+	blockDevices := EGBlockDevices{Root: root}
+
+	// WHEN
+	name := blockDevices.Name("8:0")
+
+	// THEN
+	assert.Equal(t, "sda", name)
+	assert.Equal(t, "sda", blockDevices.M["8:0"])
+}
+
+func TestBlockDevicesNameUnknownDevice(t *testing.T) {
+	// GIVEN
+	root, err := ioutil.TempDir("", "dockbeat-block")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	blockDevices := EGBlockDevices{Root: root}
+
+	// WHEN
+	name := blockDevices.Name("253:1")
+
+	// THEN
+	// the device id is used as name
+	assert.Equal(t, "253:1", name)
+}
@@ -0,0 +1,151 @@
+package event
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// EGContainerChanges keeps the fingerprint of the last published container event of each container,
+// to publish container events only when they change.
+type EGContainerChanges struct {
+	sync.Mutex
+	// Enabled activates the change-only mode; when disabled, a container event is published at every tick
+	Enabled bool
+	// Heartbeat is the maximum delay between two events of an unchanged container; 0 disables the heartbeat
+	Heartbeat time.Duration
+	M         map[string]containerFingerprint
+}
+
+type containerFingerprint struct {
+	fields    map[string]string
+	published time.Time
+}
+
+// statusDetails matches the parts of the container status which are not durations, e.g. "(0)" in
+// "Exited (0) 3 hours ago" or "(healthy)" in "Up 2 minutes (healthy)"
+var statusDetails = regexp.MustCompile(`\([^)]*\)`)
+
+/*
+GetChangedContainerEvent returns the container event only if one of its fields changed since the last published one,
+or if the heartbeat delay elapsed; otherwise it returns nil. The event contains the list of changed fields and a hash
+of all tracked fields. The sizes are tracked once they are collected, and only the state part of the status is tracked
+as the status contains the container uptime ("Up 5 minutes").
+
+When the change-only mode is disabled, the container event is always returned.
+*/
+func (d *EventGenerator) GetChangedContainerEvent(container *docker.APIContainers, stats *docker.Stats) common.MapStr {
+	event := d.GetContainerEvent(container, stats)
+	if !d.ContainerChanges.Enabled {
+		return event
+	}
+
+	fields := d.containerFingerprintFields(container)
+	now := stats.Read
+
+	d.ContainerChanges.Lock()
+	defer d.ContainerChanges.Unlock()
+	if d.ContainerChanges.M == nil {
+		d.ContainerChanges.M = map[string]containerFingerprint{}
+	}
+
+	previous, exists := d.ContainerChanges.M[container.ID]
+	changes := []string{}
+	for name, value := range fields {
+		if !exists || previous.fields[name] != value {
+			changes = append(changes, name)
+		}
+	}
+	sort.Strings(changes)
+
+	heartbeat := exists && d.ContainerChanges.Heartbeat > 0 && !previous.published.Add(d.ContainerChanges.Heartbeat).After(now)
+	if len(changes) == 0 && !heartbeat {
+		logp.Debug("generator", "Container %v did not change", container.ID)
+		return nil
+	}
+
+	d.ContainerChanges.M[container.ID] = containerFingerprint{fields: fields, published: now}
+
+	section := event["container"].(common.MapStr)
+	section["changes"] = changes
+	section["hash"] = hashFingerprintFields(fields)
+	return event
+}
+
+// containerFingerprintFields serializes each tracked field of the container; encoding/json sorts map keys,
+// so that labels always give the same value.
+func (d *EventGenerator) containerFingerprintFields(container *docker.APIContainers) map[string]string {
+	values := map[string]interface{}{
+		"command": container.Command,
+		"created": container.Created,
+		"image":   container.Image,
+		"labels":  container.Labels,
+		"names":   container.Names,
+		"ports":   d.convertContainerPorts(&container.Ports),
+		"state":   containerState(container),
+	}
+	// the sizes are collected on their own schedule, a collection which changed them is a change
+	d.ContainerSizes.RLock()
+	if size, ok := d.ContainerSizes.M[container.ID]; ok {
+		values["sizeRw"] = size.sizeRw
+		values["sizeRootFs"] = size.sizeRootFs
+	}
+	d.ContainerSizes.RUnlock()
+
+	fields := map[string]string{}
+	for name, value := range values {
+		serialized, err := json.Marshal(value)
+		if err != nil {
+			logp.Warn("Unable to serialize field %v of container %v: %v", name, container.ID, err)
+			continue
+		}
+		fields[name] = string(serialized)
+	}
+	return fields
+}
+
+// containerState returns the state of the container (the first word of the status with old docker versions)
+// followed by the status details, e.g. "running (healthy)"
+func containerState(container *docker.APIContainers) string {
+	state := container.State
+	if state == "" {
+		if words := strings.Fields(container.Status); len(words) > 0 {
+			state = words[0]
+		}
+	}
+	return strings.Join(append([]string{state}, statusDetails.FindAllString(container.Status, -1)...), " ")
+}
+
+func hashFingerprintFields(fields map[string]string) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha1.New()
+	for _, name := range names {
+		hash.Write([]byte(name + "=" + fields[name] + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// clean removes the fingerprints of the containers which are gone
+func (c *EGContainerChanges) clean(live map[string]bool) {
+	c.Lock()
+	defer c.Unlock()
+	for id := range c.M {
+		if !live[id] {
+			delete(c.M, id)
+		}
+	}
+}
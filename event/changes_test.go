@@ -0,0 +1,139 @@
+package event
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/ingensi/dockbeat/calculator"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEventGeneratorGetChangedContainerEventDisabled(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := getChangesContainer()
+	stats := &docker.Stats{Read: time.Now()}
+	var eventGenerator = EventGenerator{Socket: &socket}
+
+	// WHEN
+	first := eventGenerator.GetChangedContainerEvent(container, stats)
+	second := eventGenerator.GetChangedContainerEvent(container, stats)
+
+	// THEN
+	// events are always generated, without changes information
+	assert.NotNil(t, first)
+	assert.NotNil(t, second)
+	assert.Nil(t, second["container"].(common.MapStr)["changes"])
+}
+
+/*
+TestEventGeneratorGetChangedContainerEvent simulates a container whose uptime increases, then whose ports change.
+*/
+func TestEventGeneratorGetChangedContainerEvent(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := getChangesContainer()
+	timestamp := time.Now()
+	var eventGenerator = EventGenerator{Socket: &socket, ContainerChanges: EGContainerChanges{Enabled: true}}
+
+	// WHEN
+	first := eventGenerator.GetChangedContainerEvent(container, &docker.Stats{Read: timestamp})
+	container.Status = "Up 2 minutes (healthy)"
+	unchanged := eventGenerator.GetChangedContainerEvent(container, &docker.Stats{Read: timestamp.Add(time.Minute)})
+	container.Ports = append(container.Ports, docker.APIPort{PrivatePort: 443, PublicPort: 8443, Type: "tcp", IP: "0.0.0.0"})
+	changed := eventGenerator.GetChangedContainerEvent(container, &docker.Stats{Read: timestamp.Add(2 * time.Minute)})
+
+	// THEN
+	// all fields are changed on the first event
+	assert.NotNil(t, first)
+	assert.Equal(t, []string{"command", "created", "image", "labels", "names", "ports", "state"}, first["container"].(common.MapStr)["changes"])
+	// status uptime is not a change
+	assert.Nil(t, unchanged)
+	assert.NotNil(t, changed)
+	assert.Equal(t, []string{"ports"}, changed["container"].(common.MapStr)["changes"])
+	assert.NotEqual(t, first["container"].(common.MapStr)["hash"], changed["container"].(common.MapStr)["hash"])
+	assert.Len(t, changed["container"].(common.MapStr)["hash"], 40)
+}
+
+func TestEventGeneratorGetChangedContainerEventHeartbeat(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := getChangesContainer()
+	timestamp := time.Now()
+	var eventGenerator = EventGenerator{Socket: &socket, ContainerChanges: EGContainerChanges{Enabled: true, Heartbeat: time.Minute}}
+
+	// WHEN
+	first := eventGenerator.GetChangedContainerEvent(container, &docker.Stats{Read: timestamp})
+	beforeHeartbeat := eventGenerator.GetChangedContainerEvent(container, &docker.Stats{Read: timestamp.Add(30 * time.Second)})
+	heartbeat := eventGenerator.GetChangedContainerEvent(container, &docker.Stats{Read: timestamp.Add(time.Minute)})
+
+	// THEN
+	assert.NotNil(t, first)
+	assert.Nil(t, beforeHeartbeat)
+	assert.NotNil(t, heartbeat)
+	assert.Equal(t, []string{}, heartbeat["container"].(common.MapStr)["changes"])
+	assert.Equal(t, first["container"].(common.MapStr)["hash"], heartbeat["container"].(common.MapStr)["hash"])
+}
+
+func TestEventGeneratorGetChangedContainerEventSizes(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := getChangesContainer()
+	timestamp := time.Now()
+	var eventGenerator = EventGenerator{Socket: &socket, ContainerChanges: EGContainerChanges{Enabled: true}}
+	container.SizeRw, container.SizeRootFs = 1024, 4096
+	eventGenerator.UpdateContainerSizes(timestamp, []docker.APIContainers{*container})
+
+	// WHEN
+	first := eventGenerator.GetChangedContainerEvent(container, &docker.Stats{Read: timestamp})
+	unchanged := eventGenerator.GetChangedContainerEvent(container, &docker.Stats{Read: timestamp.Add(time.Minute)})
+	container.SizeRw = 2048
+	eventGenerator.UpdateContainerSizes(timestamp.Add(2*time.Minute), []docker.APIContainers{*container})
+	changed := eventGenerator.GetChangedContainerEvent(container, &docker.Stats{Read: timestamp.Add(2 * time.Minute)})
+
+	// THEN
+	assert.NotNil(t, first)
+	assert.Contains(t, first["container"].(common.MapStr)["changes"], "sizeRootFs")
+	assert.Nil(t, unchanged)
+	assert.NotNil(t, changed)
+	assert.Equal(t, []string{"sizeRw"}, changed["container"].(common.MapStr)["changes"])
+	assert.Equal(t, int64(2048), changed["container"].(common.MapStr)["sizeRw"])
+}
+
+func TestEventGeneratorGetChangedContainerEventCleaned(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := getChangesContainer()
+	var eventGenerator = EventGenerator{
+		Socket:           &socket,
+		NetworkStats:     EGNetworkStats{M: map[string]map[string]calculator.NetworkData{}},
+		ContainerChanges: EGContainerChanges{Enabled: true},
+	}
+	eventGenerator.GetChangedContainerEvent(container, &docker.Stats{Read: time.Now()})
+
+	// WHEN
+	eventGenerator.CleanOldStats(map[string]bool{})
+
+	// THEN
+	assert.Empty(t, eventGenerator.ContainerChanges.M)
+}
+
+func TestContainerState(t *testing.T) {
+	assert.Equal(t, "running (healthy)", containerState(&docker.APIContainers{State: "running", Status: "Up 3 hours (healthy)"}))
+	assert.Equal(t, "Exited (0)", containerState(&docker.APIContainers{Status: "Exited (0) 3 hours ago"}))
+	assert.Equal(t, "Up", containerState(&docker.APIContainers{Status: "Up About an hour"}))
+}
+
+func getChangesContainer() *docker.APIContainers {
+	return &docker.APIContainers{
+		ID:      "container_id",
+		Image:   "nginx",
+		Command: "nginx -g daemon off;",
+		Created: 1464775200,
+		Status:  "Up 1 minute (healthy)",
+		Names:   []string{"/web"},
+		Labels:  map[string]string{"team": "core", "env": "prod"},
+		Ports:   []docker.APIPort{{PrivatePort: 80, PublicPort: 8080, Type: "tcp", IP: "0.0.0.0"}},
+	}
+}
@@ -0,0 +1,147 @@
+package event
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// The docker restart policy waits 100ms before the first restart, then doubles the delay at each restart,
+// up to one minute. The delay is reset when the container ran for more than 10 seconds.
+const (
+	restartBackoffMin = 100 * time.Millisecond
+	restartBackoffMax = time.Minute
+	// maxExitCodes is the number of last exit codes reported
+	maxExitCodes = 5
+)
+
+// EGCrashLoops holds the restart history of each container. A container is in a crash loop when it restarted more
+// than Restarts times in the last Window.
+type EGCrashLoops struct {
+	sync.Mutex
+	Restarts int
+	Window   time.Duration
+	M        map[string]*restartHistory
+}
+
+type restartHistory struct {
+	restartCount int
+	finishedAt   time.Time
+	// restarts are the restart times within the window
+	restarts  []time.Time
+	exitCodes []int
+	looping   bool
+}
+
+/*
+GetCrashLoopEvent tracks the restarts of a container, from the RestartCount and the start and finish times of its
+inspected state, and returns a crashloop event when the container enters a crash loop. It returns nil otherwise:
+a single event is generated until the container leaves the crash loop.
+
+The exit codes are the last ones observed: docker resets the exit code when the container starts again, so exits
+the container restarted from between two ticks have no exit code.
+*/
+func (d *EventGenerator) GetCrashLoopEvent(container *docker.APIContainers, stats *docker.Stats, info *docker.Container) common.MapStr {
+	logp.Debug("generator", "Generate crashloop event %v", container.ID)
+	d.CrashLoops.Lock()
+	defer d.CrashLoops.Unlock()
+	if d.CrashLoops.M == nil {
+		d.CrashLoops.M = map[string]*restartHistory{}
+	}
+
+	history, exists := d.CrashLoops.M[container.ID]
+	if !exists {
+		// restarts before the first tick are not dated, they are ignored
+		history = &restartHistory{restartCount: info.RestartCount, exitCodes: []int{}}
+		d.CrashLoops.M[container.ID] = history
+	}
+
+	// docker keeps Running while the container is restarting
+	state := info.State
+	if (state.Restarting || !state.Running) && !state.FinishedAt.IsZero() && !state.FinishedAt.Equal(history.finishedAt) {
+		history.exitCodes = append(history.exitCodes, state.ExitCode)
+		if len(history.exitCodes) > maxExitCodes {
+			history.exitCodes = history.exitCodes[len(history.exitCodes)-maxExitCodes:]
+		}
+	}
+	history.finishedAt = state.FinishedAt
+
+	// the restart count is reset when the container is restarted manually
+	if info.RestartCount > history.restartCount {
+		restartedAt := state.StartedAt
+		if restartedAt.IsZero() || restartedAt.Before(stats.Read.Add(-d.CrashLoops.Window)) {
+			restartedAt = stats.Read
+		}
+		for i := history.restartCount; i < info.RestartCount; i++ {
+			history.restarts = append(history.restarts, restartedAt)
+		}
+	}
+	history.restartCount = info.RestartCount
+
+	windowStart := stats.Read.Add(-d.CrashLoops.Window)
+	for len(history.restarts) > 0 && history.restarts[0].Before(windowStart) {
+		history.restarts = history.restarts[1:]
+	}
+
+	if len(history.restarts) <= d.CrashLoops.Restarts {
+		history.looping = false
+		return nil
+	}
+	if history.looping {
+		return nil
+	}
+	history.looping = true
+
+	exitCodes := make([]int, len(history.exitCodes))
+	copy(exitCodes, history.exitCodes)
+	crashloop := common.MapStr{
+		"restarts":     len(history.restarts),
+		"window":       d.CrashLoops.Window.Seconds(),
+		"restartCount": info.RestartCount,
+		"exitCodes":    exitCodes,
+		"restarting":   state.Restarting,
+		"backoff":      restartBackoff(len(history.restarts)).Seconds(),
+		"startedAt":    common.Time(state.StartedAt),
+	}
+	if !state.FinishedAt.IsZero() {
+		crashloop["finishedAt"] = common.Time(state.FinishedAt)
+	}
+
+	return common.MapStr{
+		"@timestamp":      common.Time(stats.Read),
+		"type":            "crashloop",
+		"containerID":     container.ID,
+		"containerName":   d.extractContainerName(container.Names),
+		"containerLabels": d.buildLabelArray(container.Labels),
+		"containerImage":  container.Image,
+		"dockerSocket":    d.Socket,
+		"crashloop":       crashloop,
+	}
+}
+
+// restartBackoff estimates the delay docker waits before the next restart of a container which restarted the given
+// number of times in a row
+func restartBackoff(restarts int) time.Duration {
+	backoff := restartBackoffMin
+	for i := 1; i < restarts && backoff < restartBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > restartBackoffMax {
+		return restartBackoffMax
+	}
+	return backoff
+}
+
+// clean removes the restart history of the containers which are gone
+func (c *EGCrashLoops) clean(live map[string]bool) {
+	c.Lock()
+	defer c.Unlock()
+	for id := range c.M {
+		if !live[id] {
+			delete(c.M, id)
+		}
+	}
+}
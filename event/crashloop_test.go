@@ -0,0 +1,106 @@
+package event
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/ingensi/dockbeat/calculator"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+/*
+TestEventGeneratorGetCrashLoopEvent simulates a container restarting every 10 seconds, seen once restarting and
+once running at each restart.
+*/
+func TestEventGeneratorGetCrashLoopEvent(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := &docker.APIContainers{ID: "container_id", Names: []string{"/worker"}}
+	timestamp := time.Now()
+	var eventGenerator = EventGenerator{Socket: &socket, CrashLoops: EGCrashLoops{Restarts: 2, Window: time.Minute}}
+
+	// WHEN
+	events := []common.MapStr{}
+	for i := 0; i < 4; i++ {
+		finishedAt := timestamp.Add(time.Duration(10*i) * time.Second)
+		startedAt := finishedAt.Add(time.Second)
+		restarting := &docker.Container{
+			RestartCount: i,
+			State:        docker.State{Running: true, Restarting: true, ExitCode: 137 - i, StartedAt: startedAt.Add(-10 * time.Second), FinishedAt: finishedAt},
+		}
+		running := &docker.Container{
+			RestartCount: i + 1,
+			State:        docker.State{Running: true, StartedAt: startedAt, FinishedAt: finishedAt},
+		}
+		events = append(events,
+			eventGenerator.GetCrashLoopEvent(container, &docker.Stats{Read: finishedAt}, restarting),
+			eventGenerator.GetCrashLoopEvent(container, &docker.Stats{Read: startedAt.Add(time.Second)}, running),
+		)
+	}
+
+	// THEN
+	// the container enters the crash loop at its third restart, the event is generated once
+	for i, event := range events {
+		if i != 5 {
+			assert.Nil(t, event, "sample %v", i)
+		}
+	}
+	assert.Equal(t, "crashloop", events[5]["type"])
+	assert.Equal(t, "worker", events[5]["containerName"])
+	assert.Equal(t, common.MapStr{
+		"restarts":     3,
+		"window":       float64(60),
+		"restartCount": 3,
+		"exitCodes":    []int{137, 136, 135},
+		"restarting":   false,
+		"backoff":      0.4,
+		"startedAt":    common.Time(timestamp.Add(21 * time.Second)),
+		"finishedAt":   common.Time(timestamp.Add(20 * time.Second)),
+	}, events[5]["crashloop"])
+}
+
+func TestEventGeneratorGetCrashLoopEventWindow(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := &docker.APIContainers{ID: "container_id", Names: []string{"/worker"}}
+	timestamp := time.Now()
+	var eventGenerator = EventGenerator{Socket: &socket, CrashLoops: EGCrashLoops{Restarts: 1, Window: time.Minute}}
+	eventGenerator.GetCrashLoopEvent(container, &docker.Stats{Read: timestamp}, &docker.Container{RestartCount: 10})
+
+	// WHEN
+	// restarts before the first tick are ignored, restarts older than the window are forgotten
+	first := eventGenerator.GetCrashLoopEvent(container, &docker.Stats{Read: timestamp.Add(time.Minute)}, &docker.Container{RestartCount: 11})
+	second := eventGenerator.GetCrashLoopEvent(container, &docker.Stats{Read: timestamp.Add(3 * time.Minute)}, &docker.Container{RestartCount: 12})
+	third := eventGenerator.GetCrashLoopEvent(container, &docker.Stats{Read: timestamp.Add(3*time.Minute + time.Second)}, &docker.Container{RestartCount: 13})
+
+	// THEN
+	assert.Nil(t, first)
+	assert.Nil(t, second)
+	assert.NotNil(t, third)
+	assert.Equal(t, 2, third["crashloop"].(common.MapStr)["restarts"])
+	assert.Nil(t, third["crashloop"].(common.MapStr)["finishedAt"])
+}
+
+func TestEventGeneratorGetCrashLoopEventCleaned(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	var eventGenerator = EventGenerator{
+		Socket:       &socket,
+		NetworkStats: EGNetworkStats{M: map[string]map[string]calculator.NetworkData{}},
+	}
+	eventGenerator.GetCrashLoopEvent(&docker.APIContainers{ID: "container_id"}, &docker.Stats{Read: time.Now()}, &docker.Container{})
+
+	// WHEN
+	eventGenerator.CleanOldStats(map[string]bool{})
+
+	// THEN
+	assert.Empty(t, eventGenerator.CrashLoops.M)
+}
+
+func TestRestartBackoff(t *testing.T) {
+	assert.Equal(t, 100*time.Millisecond, restartBackoff(1))
+	assert.Equal(t, 800*time.Millisecond, restartBackoff(4))
+	assert.Equal(t, time.Minute, restartBackoff(11))
+	assert.Equal(t, time.Minute, restartBackoff(1000))
+}
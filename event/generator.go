@@ -5,6 +5,10 @@ import (
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/fsouza/go-dockerclient"
 	"github.com/ingensi/dockbeat/calculator"
+	"github.com/ingensi/dockbeat/cgroup"
+	"github.com/ingensi/dockbeat/procfs"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,11 +19,21 @@ type EGNetworkStats struct {
 	M map[string]map[string]calculator.NetworkData
 }
 
+type EGCpuStats struct {
+	sync.RWMutex
+	M map[string]calculator.CPUData
+}
+
 type EGBlkioStats struct {
 	sync.RWMutex
 	M map[string]calculator.BlkioData
 }
 
+type EGPressureStats struct {
+	sync.RWMutex
+	M map[string]cgroup.Pressure
+}
+
 type Label struct {
 	key   string
 	value string
@@ -28,7 +42,16 @@ type Label struct {
 type EventGenerator struct {
 	Socket            *string
 	NetworkStats      EGNetworkStats
+	CpuStats          EGCpuStats
 	BlkioStats        EGBlkioStats
+	PressureStats     EGPressureStats
+	BlockDevices      EGBlockDevices
+	Rollup            EGRollup
+	ContainerChanges  EGContainerChanges
+	MemorySamples     EGMemorySamples
+	Rules             EGRules
+	CrashLoops        EGCrashLoops
+	ContainerSizes    EGContainerSizes
 	CalculatorFactory calculator.CalculatorFactory
 	Period            time.Duration
 }
@@ -41,6 +64,7 @@ func (d *EventGenerator) GetContainerEvent(container *docker.APIContainers, stat
 		"containerID":     container.ID,
 		"containerName":   d.extractContainerName(container.Names),
 		"containerLabels": d.buildLabelArray(container.Labels),
+		"containerImage":  container.Image,
 		"dockerSocket":    d.Socket,
 		"container": common.MapStr{
 			"id":         container.ID,
@@ -54,25 +78,45 @@ func (d *EventGenerator) GetContainerEvent(container *docker.APIContainers, stat
 			"status":     container.Status,
 		},
 	}
+	// containers are not listed with their sizes at each tick, the last collected ones are used
+	d.addContainerSize(container.ID, event["container"].(common.MapStr))
 	return event
 }
 
 func (d *EventGenerator) GetCpuEvent(container *docker.APIContainers, stats *docker.Stats) common.MapStr {
 	logp.Debug("generator", "Generate cpu event %v", container.ID)
-	calculator := d.CalculatorFactory.NewCPUCalculator(
-		calculator.CPUData{
+	newCPUData := calculator.CPUData{
+		PerCpuUsage:       stats.CPUStats.CPUUsage.PercpuUsage,
+		TotalUsage:        stats.CPUStats.CPUUsage.TotalUsage,
+		UsageInKernelmode: stats.CPUStats.CPUUsage.UsageInKernelmode,
+		UsageInUsermode:   stats.CPUStats.CPUUsage.UsageInUsermode,
+		Time:              stats.Read,
+	}
+
+	d.CpuStats.Lock()
+	oldCPUData, ok := d.CpuStats.M[container.ID]
+	if !ok {
+		// without a previous tick, the precpu stats are used: docker samples them one second before the cpu stats
+		// when the stats are not streamed
+		oldCPUData = calculator.CPUData{
 			PerCpuUsage:       stats.PreCPUStats.CPUUsage.PercpuUsage,
 			TotalUsage:        stats.PreCPUStats.CPUUsage.TotalUsage,
 			UsageInKernelmode: stats.PreCPUStats.CPUUsage.UsageInKernelmode,
 			UsageInUsermode:   stats.PreCPUStats.CPUUsage.UsageInUsermode,
-		},
-		calculator.CPUData{
-			PerCpuUsage:       stats.CPUStats.CPUUsage.PercpuUsage,
-			TotalUsage:        stats.CPUStats.CPUUsage.TotalUsage,
-			UsageInKernelmode: stats.CPUStats.CPUUsage.UsageInKernelmode,
-			UsageInUsermode:   stats.CPUStats.CPUUsage.UsageInUsermode,
-		},
-	)
+			Time:              stats.Read.Add(-time.Second),
+		}
+	}
+	d.CpuStats.M[container.ID] = newCPUData
+	// purge old saved data
+	for containerId, cpuData := range d.CpuStats.M {
+		// if data older than two ticks, then delete it
+		if d.expiredSavedData(cpuData.Time) {
+			delete(d.CpuStats.M, containerId)
+		}
+	}
+	d.CpuStats.Unlock()
+
+	calculator := d.CalculatorFactory.NewCPUCalculator(oldCPUData, newCPUData)
 
 	event := common.MapStr{
 		"@timestamp":      common.Time(stats.Read),
@@ -80,12 +124,14 @@ func (d *EventGenerator) GetCpuEvent(container *docker.APIContainers, stats *doc
 		"containerID":     container.ID,
 		"containerName":   d.extractContainerName(container.Names),
 		"containerLabels": d.buildLabelArray(container.Labels),
+		"containerImage":  container.Image,
 		"dockerSocket":    d.Socket,
 		"cpu": common.MapStr{
 			"percpuUsage":       calculator.PerCpuUsage(),
 			"totalUsage":        calculator.TotalUsage(),
 			"usageInKernelmode": calculator.UsageInKernelmode(),
 			"usageInUsermode":   calculator.UsageInUsermode(),
+			"counterReset":      calculator.CounterReset(),
 		},
 	}
 
@@ -151,9 +197,18 @@ func (d *EventGenerator) GetNetworkEvent(container *docker.APIContainers, time t
 			"containerID":     container.ID,
 			"containerName":   d.extractContainerName(container.Names),
 			"containerLabels": d.buildLabelArray(container.Labels),
+			"containerImage":  container.Image,
 			"dockerSocket":    d.Socket,
 			"net": common.MapStr{
 				"name":         network,
+				"rxBytes":      newNetworkData.RxBytes,
+				"rxDropped":    newNetworkData.RxDropped,
+				"rxErrors":     newNetworkData.RxErrors,
+				"rxPackets":    newNetworkData.RxPackets,
+				"txBytes":      newNetworkData.TxBytes,
+				"txDropped":    newNetworkData.TxDropped,
+				"txErrors":     newNetworkData.TxErrors,
+				"txPackets":    newNetworkData.TxPackets,
 				"rxBytes_ps":   calculator.GetRxBytesPerSecond(),
 				"rxDropped_ps": calculator.GetRxDroppedPerSecond(),
 				"rxErrors_ps":  calculator.GetRxErrorsPerSecond(),
@@ -162,6 +217,7 @@ func (d *EventGenerator) GetNetworkEvent(container *docker.APIContainers, time t
 				"txDropped_ps": calculator.GetTxDroppedPerSecond(),
 				"txErrors_ps":  calculator.GetTxErrorsPerSecond(),
 				"txPackets_ps": calculator.GetTxPacketsPerSecond(),
+				"counterReset": calculator.CounterReset(),
 			},
 		}
 	} else {
@@ -171,17 +227,27 @@ func (d *EventGenerator) GetNetworkEvent(container *docker.APIContainers, time t
 			"containerID":     container.ID,
 			"containerName":   d.extractContainerName(container.Names),
 			"containerLabels": d.buildLabelArray(container.Labels),
+			"containerImage":  container.Image,
 			"dockerSocket":    d.Socket,
 			"net": common.MapStr{
 				"name":         network,
-				"rxBytes_ps":   0,
-				"rxDropped_ps": 0,
-				"rxErrors_ps":  0,
-				"rxPackets_ps": 0,
-				"txBytes_ps":   0,
-				"txDropped_ps": 0,
-				"txErrors_ps":  0,
-				"txPackets_ps": 0,
+				"rxBytes":      newNetworkData.RxBytes,
+				"rxDropped":    newNetworkData.RxDropped,
+				"rxErrors":     newNetworkData.RxErrors,
+				"rxPackets":    newNetworkData.RxPackets,
+				"txBytes":      newNetworkData.TxBytes,
+				"txDropped":    newNetworkData.TxDropped,
+				"txErrors":     newNetworkData.TxErrors,
+				"txPackets":    newNetworkData.TxPackets,
+				"rxBytes_ps":   float64(0),
+				"rxDropped_ps": float64(0),
+				"rxErrors_ps":  float64(0),
+				"rxPackets_ps": float64(0),
+				"txBytes_ps":   float64(0),
+				"txDropped_ps": float64(0),
+				"txErrors_ps":  float64(0),
+				"txPackets_ps": float64(0),
+				"counterReset": false,
 			},
 		}
 	}
@@ -204,6 +270,7 @@ func (d *EventGenerator) GetMemoryEvent(container *docker.APIContainers, stats *
 		"containerID":     container.ID,
 		"containerName":   d.extractContainerName(container.Names),
 		"containerLabels": d.buildLabelArray(container.Labels),
+		"containerImage":  container.Image,
 		"dockerSocket":    d.Socket,
 		"memory": common.MapStr{
 			"failcnt":    stats.MemoryStats.Failcnt,
@@ -219,9 +286,257 @@ func (d *EventGenerator) GetMemoryEvent(container *docker.APIContainers, stats *
 	return event
 }
 
+// AddMemoryEvents adds to a memory event the memory.events counters of cgroup v2: how many times the usage
+// reached the low, high and max boundaries, and how many times the OOM killer was invoked or killed a process.
+func (d *EventGenerator) AddMemoryEvents(event common.MapStr, memoryEvents map[string]uint64) {
+	events := common.MapStr{}
+	for _, name := range []string{"low", "high", "max", "oom", "oom_kill"} {
+		events[name] = memoryEvents[name]
+	}
+	event["memory"].(common.MapStr)["events"] = events
+}
+
+/*
+AddMemoryLimit adds to a memory event whether the container has a memory limit (from inspect). Without limit, the
+limit reported by docker is the host memory, so usage_p is not a usage of the limit: hostUsage_p gives the usage
+in percents of the host total memory instead, when it is known.
+*/
+func (d *EventGenerator) AddMemoryLimit(event common.MapStr, stats *docker.Stats, info *docker.Container, hostMemTotal int64) {
+	memory := event["memory"].(common.MapStr)
+	limited := info.HostConfig != nil && info.HostConfig.Memory > 0
+	memory["limited"] = limited
+	if !limited && hostMemTotal > 0 {
+		memory["hostUsage_p"] = float64(stats.MemoryStats.Usage) / float64(hostMemTotal)
+	}
+}
+
+func (d *EventGenerator) GetPidsEvent(container *docker.APIContainers, stats *docker.Stats, info *docker.Container) common.MapStr {
+	logp.Debug("generator", "Generate pids event %v", container.ID)
+	pids := common.MapStr{
+		"current": stats.PidsStats.Current,
+		"limit":   int64(0),
+	}
+
+	// a zero or negative limit means the container can create as many processes as it wants
+	if info.HostConfig != nil && info.HostConfig.PidsLimit > 0 {
+		pids["limit"] = info.HostConfig.PidsLimit
+		pids["usage_p"] = float64(stats.PidsStats.Current) / float64(info.HostConfig.PidsLimit)
+	}
+
+	event := common.MapStr{
+		"@timestamp":      common.Time(stats.Read),
+		"type":            "pids",
+		"containerID":     container.ID,
+		"containerName":   d.extractContainerName(container.Names),
+		"containerLabels": d.buildLabelArray(container.Labels),
+		"containerImage":  container.Image,
+		"dockerSocket":    d.Socket,
+		"pids":            pids,
+	}
+
+	return event
+}
+
+/*
+GetPressureEvent generates the pressure stall information event of a container. For each resource (cpu, memory, io),
+"some" is the share of time at least one task was stalled on the resource and "full" the share of time all tasks
+were stalled at the same time. The stall rate is computed from the previous pressure of the container.
+*/
+func (d *EventGenerator) GetPressureEvent(container *docker.APIContainers, pressure *cgroup.Pressure) common.MapStr {
+	logp.Debug("generator", "Generate pressure event %v", container.ID)
+
+	d.PressureStats.Lock()
+	if d.PressureStats.M == nil {
+		d.PressureStats.M = map[string]cgroup.Pressure{}
+	}
+	oldPressure, ok := d.PressureStats.M[container.ID]
+	d.PressureStats.M[container.ID] = *pressure
+	d.PressureStats.Unlock()
+
+	rate := calculator.NewRate(oldPressure.Time, pressure.Time)
+	buildResource := func(oldStats map[string]cgroup.PressureStats, newStats map[string]cgroup.PressureStats) common.MapStr {
+		resource := common.MapStr{}
+		for name, stats := range newStats {
+			// total is a stall time in microseconds, so the rate per second is the share of time stalled
+			stalled := float64(0)
+			if old, exists := oldStats[name]; ok && exists {
+				stalled = rate.PerSecond(old.Total, stats.Total) / float64(time.Second/time.Microsecond)
+			}
+			resource[name] = common.MapStr{
+				"avg10":     stats.Avg10,
+				"avg60":     stats.Avg60,
+				"avg300":    stats.Avg300,
+				"total":     stats.Total,
+				"stalled_p": stalled,
+			}
+		}
+		return resource
+	}
+
+	event := common.MapStr{
+		"@timestamp":      common.Time(pressure.Time),
+		"type":            "pressure",
+		"containerID":     container.ID,
+		"containerName":   d.extractContainerName(container.Names),
+		"containerLabels": d.buildLabelArray(container.Labels),
+		"containerImage":  container.Image,
+		"dockerSocket":    d.Socket,
+		"pressure": common.MapStr{
+			"cpu":    buildResource(oldPressure.CPU, pressure.CPU),
+			"memory": buildResource(oldPressure.Memory, pressure.Memory),
+			"io":     buildResource(oldPressure.IO, pressure.IO),
+		},
+	}
+
+	return event
+}
+
+// GetTcpEvent generates the event counting the TCP connections (IPv4 and IPv6) of a container by state
+func (d *EventGenerator) GetTcpEvent(container *docker.APIContainers, stats *docker.Stats, sockets []procfs.Socket) common.MapStr {
+	logp.Debug("generator", "Generate tcp event %v", container.ID)
+	tcp := common.MapStr{"total": len(sockets)}
+	for _, state := range procfs.TCPStates {
+		tcp[strings.ToLower(state)] = 0
+	}
+	for _, socket := range sockets {
+		if state := strings.ToLower(socket.State); state != "" {
+			tcp[state] = tcp[state].(int) + 1
+		}
+	}
+
+	event := common.MapStr{
+		"@timestamp":      common.Time(stats.Read),
+		"type":            "tcp",
+		"containerID":     container.ID,
+		"containerName":   d.extractContainerName(container.Names),
+		"containerLabels": d.buildLabelArray(container.Labels),
+		"containerImage":  container.Image,
+		"dockerSocket":    d.Socket,
+		"tcp":             tcp,
+	}
+
+	return event
+}
+
+/*
+GetListenEvent generates the inventory of the sockets a container listens on, compared to its published ports:
+  - each listening socket is flagged as published or not (a published port has a public port on the host)
+  - published ports without any listening socket are listed in unusedPorts
+*/
+func (d *EventGenerator) GetListenEvent(container *docker.APIContainers, stats *docker.Stats, sockets []procfs.Socket) common.MapStr {
+	logp.Debug("generator", "Generate listen event %v", container.ID)
+
+	// protocol (tcp or udp) and port of the published ports
+	published := map[string]bool{}
+	for _, port := range container.Ports {
+		if port.PublicPort > 0 {
+			published[port.Type+"/"+strconv.FormatInt(port.PrivatePort, 10)] = true
+		}
+	}
+
+	listening := map[string]bool{}
+	outputSockets := []common.MapStr{}
+	unpublished := 0
+	for _, socket := range sockets {
+		// tcp6 and udp6 sockets also accept IPv4 connections, so they are reported as tcp and udp
+		protocol := strings.TrimSuffix(socket.Protocol, "6")
+		key := protocol + "/" + strconv.Itoa(socket.LocalPort)
+		listening[key] = true
+		if !published[key] {
+			unpublished++
+		}
+		outputSockets = append(outputSockets, common.MapStr{
+			"protocol":  protocol,
+			"address":   socket.LocalAddress.String(),
+			"port":      socket.LocalPort,
+			"published": published[key],
+		})
+	}
+
+	unusedPorts := []common.MapStr{}
+	for _, port := range container.Ports {
+		if port.PublicPort > 0 && !listening[port.Type+"/"+strconv.FormatInt(port.PrivatePort, 10)] {
+			unusedPorts = append(unusedPorts, common.MapStr{
+				"protocol":    port.Type,
+				"ip":          port.IP,
+				"privatePort": port.PrivatePort,
+				"publicPort":  port.PublicPort,
+			})
+		}
+	}
+
+	event := common.MapStr{
+		"@timestamp":      common.Time(stats.Read),
+		"type":            "listen",
+		"containerID":     container.ID,
+		"containerName":   d.extractContainerName(container.Names),
+		"containerLabels": d.buildLabelArray(container.Labels),
+		"containerImage":  container.Image,
+		"dockerSocket":    d.Socket,
+		"listen": common.MapStr{
+			"sockets":     outputSockets,
+			"unpublished": unpublished,
+			"unusedPorts": unusedPorts,
+			"unused":      len(unusedPorts),
+		},
+	}
+
+	return event
+}
+
+/*
+GetFdEvent generates the file descriptors usage event of a container, from the usage of each of its processes.
+As the open files limit applies to each process, the process closest to its limit is reported: the one with the
+highest usage ratio, or the one with the most open file descriptors when no process has a limit.
+*/
+func (d *EventGenerator) GetFdEvent(container *docker.APIContainers, stats *docker.Stats, processes []procfs.FileDescriptors) common.MapStr {
+	logp.Debug("generator", "Generate fd event %v", container.ID)
+	open := uint64(0)
+	var highest *procfs.FileDescriptors
+	highestRatio := float64(0)
+	for i, process := range processes {
+		open += process.Open
+		ratio := float64(0)
+		if process.Limit > 0 {
+			ratio = float64(process.Open) / float64(process.Limit)
+		}
+		if highest == nil || ratio > highestRatio || (ratio == highestRatio && process.Open > highest.Open) {
+			highest = &processes[i]
+			highestRatio = ratio
+		}
+	}
+
+	fd := common.MapStr{
+		"open":      open,
+		"processes": len(processes),
+		"usage_p":   highestRatio,
+	}
+	if highest != nil {
+		fd["highest"] = common.MapStr{
+			"pid":     highest.Pid,
+			"open":    highest.Open,
+			"limit":   highest.Limit,
+			"usage_p": highestRatio,
+		}
+	}
+
+	event := common.MapStr{
+		"@timestamp":      common.Time(stats.Read),
+		"type":            "fd",
+		"containerID":     container.ID,
+		"containerName":   d.extractContainerName(container.Names),
+		"containerLabels": d.buildLabelArray(container.Labels),
+		"containerImage":  container.Image,
+		"dockerSocket":    d.Socket,
+		"fd":              fd,
+	}
+
+	return event
+}
+
 func (d *EventGenerator) GetBlkioEvent(container *docker.APIContainers, stats *docker.Stats) common.MapStr {
 	logp.Debug("generator", "Generate blkio event %v", container.ID)
-	blkioStats := d.buildStats(stats.Read, stats.BlkioStats.IOServicedRecursive)
+	blkioStats := d.buildStats(stats)
 
 	var event common.MapStr
 
@@ -237,11 +552,28 @@ func (d *EventGenerator) GetBlkioEvent(container *docker.APIContainers, stats *d
 			"containerID":     container.ID,
 			"containerName":   d.extractContainerName(container.Names),
 			"containerLabels": d.buildLabelArray(container.Labels),
+			"containerImage":  container.Image,
 			"dockerSocket":    d.Socket,
 			"blkio": common.MapStr{
-				"read_ps":  calculator.GetReadPs(),
-				"write_ps": calculator.GetWritePs(),
-				"total_ps": calculator.GetTotalPs(),
+				"read":              blkioStats.Reads,
+				"write":             blkioStats.Writes,
+				"total":             blkioStats.Totals,
+				"readBytes":         blkioStats.ReadBytes,
+				"writeBytes":        blkioStats.WriteBytes,
+				"totalBytes":        blkioStats.TotalBytes,
+				"read_ps":           calculator.GetReadPs(),
+				"write_ps":          calculator.GetWritePs(),
+				"total_ps":          calculator.GetTotalPs(),
+				"readBytes_ps":      calculator.GetReadBytesPs(),
+				"writeBytes_ps":     calculator.GetWriteBytesPs(),
+				"totalBytes_ps":     calculator.GetTotalBytesPs(),
+				"avgServiceTime_ms": calculator.GetAvgServiceTime(),
+				"avgWaitTime_ms":    calculator.GetAvgWaitTime(),
+				"queued":            blkioStats.Queued,
+				"merged_ps":         calculator.GetMergedPs(),
+				"sectors_ps":        calculator.GetSectorsPs(),
+				"counterReset":      calculator.CounterReset(),
+				"devices":           d.buildBlkioDevices(oldBlkioStats.Devices, blkioStats.Devices),
 			},
 		}
 	} else {
@@ -251,11 +583,28 @@ func (d *EventGenerator) GetBlkioEvent(container *docker.APIContainers, stats *d
 			"containerID":     container.ID,
 			"containerName":   d.extractContainerName(container.Names),
 			"containerLabels": d.buildLabelArray(container.Labels),
+			"containerImage":  container.Image,
 			"dockerSocket":    d.Socket,
 			"blkio": common.MapStr{
-				"read_ps":  float64(0),
-				"write_ps": float64(0),
-				"total_ps": float64(0),
+				"read":              blkioStats.Reads,
+				"write":             blkioStats.Writes,
+				"total":             blkioStats.Totals,
+				"readBytes":         blkioStats.ReadBytes,
+				"writeBytes":        blkioStats.WriteBytes,
+				"totalBytes":        blkioStats.TotalBytes,
+				"read_ps":           float64(0),
+				"write_ps":          float64(0),
+				"total_ps":          float64(0),
+				"readBytes_ps":      float64(0),
+				"writeBytes_ps":     float64(0),
+				"totalBytes_ps":     float64(0),
+				"avgServiceTime_ms": float64(0),
+				"avgWaitTime_ms":    float64(0),
+				"queued":            blkioStats.Queued,
+				"merged_ps":         float64(0),
+				"sectors_ps":        float64(0),
+				"counterReset":      false,
+				"devices":           d.buildBlkioDevices(nil, blkioStats.Devices),
 			},
 		}
 	}
@@ -274,6 +623,56 @@ func (d *EventGenerator) GetBlkioEvent(container *docker.APIContainers, stats *d
 	return event
 }
 
+// buildBlkioDevices generates the per device part of the blkio event, sorted by device id.
+// Devices without saved data get zero values.
+func (d *EventGenerator) buildBlkioDevices(oldDevices map[string]calculator.BlkioData, newDevices map[string]calculator.BlkioData) []common.MapStr {
+	ids := make([]string, 0, len(newDevices))
+	for id := range newDevices {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	output := make([]common.MapStr, 0, len(ids))
+	for _, id := range ids {
+		device := common.MapStr{
+			"id":                id,
+			"name":              d.BlockDevices.Name(id),
+			"read":              newDevices[id].Reads,
+			"write":             newDevices[id].Writes,
+			"total":             newDevices[id].Totals,
+			"readBytes":         newDevices[id].ReadBytes,
+			"writeBytes":        newDevices[id].WriteBytes,
+			"totalBytes":        newDevices[id].TotalBytes,
+			"read_ps":           float64(0),
+			"write_ps":          float64(0),
+			"total_ps":          float64(0),
+			"readBytes_ps":      float64(0),
+			"writeBytes_ps":     float64(0),
+			"totalBytes_ps":     float64(0),
+			"avgServiceTime_ms": float64(0),
+			"avgWaitTime_ms":    float64(0),
+			"queued":            newDevices[id].Queued,
+			"merged_ps":         float64(0),
+			"sectors_ps":        float64(0),
+		}
+		if oldDevice, ok := oldDevices[id]; ok {
+			calculator := d.CalculatorFactory.NewBlkioCalculator(oldDevice, newDevices[id])
+			device["read_ps"] = calculator.GetReadPs()
+			device["write_ps"] = calculator.GetWritePs()
+			device["total_ps"] = calculator.GetTotalPs()
+			device["readBytes_ps"] = calculator.GetReadBytesPs()
+			device["writeBytes_ps"] = calculator.GetWriteBytesPs()
+			device["totalBytes_ps"] = calculator.GetTotalBytesPs()
+			device["avgServiceTime_ms"] = calculator.GetAvgServiceTime()
+			device["avgWaitTime_ms"] = calculator.GetAvgWaitTime()
+			device["merged_ps"] = calculator.GetMergedPs()
+			device["sectors_ps"] = calculator.GetSectorsPs()
+		}
+		output = append(output, device)
+	}
+	return output
+}
+
 func (d *EventGenerator) GetLogEvent(level string, message string) common.MapStr {
 	logp.Debug("generator", "Generate log event with message: %v", message)
 	event := common.MapStr{
@@ -303,35 +702,109 @@ func (d *EventGenerator) convertContainerPorts(ports *[]docker.APIPort) []map[st
 	return outputPorts
 }
 
-func (d *EventGenerator) CleanOldStats(containers []docker.APIContainers) {
-	found := false
+// ContainerIDs returns the set of the IDs of the listed containers, built once per tick to clean the saved data of
+// the containers which are gone
+func ContainerIDs(containers []docker.APIContainers) map[string]bool {
+	ids := make(map[string]bool, len(containers))
+	for _, container := range containers {
+		ids[container.ID] = true
+	}
+	return ids
+}
+
+// CleanOldStats removes the saved stats of the containers which are not in live (see ContainerIDs), and returns the
+// resolved alerts of the rules which were firing for them
+func (d *EventGenerator) CleanOldStats(live map[string]bool) []common.MapStr {
 	d.NetworkStats.Lock()
-	for containerStatKey := range d.NetworkStats.M {
-		for _, container := range containers {
-			if container.ID == containerStatKey {
-				found = true
-				continue
-			}
-		}
-		if !found {
-			delete(d.NetworkStats.M, containerStatKey)
+	for id := range d.NetworkStats.M {
+		if !live[id] {
+			delete(d.NetworkStats.M, id)
 		}
 	}
 	d.NetworkStats.Unlock()
+
+	d.CpuStats.Lock()
+	for id := range d.CpuStats.M {
+		if !live[id] {
+			delete(d.CpuStats.M, id)
+		}
+	}
+	d.CpuStats.Unlock()
+
+	d.PressureStats.Lock()
+	for id := range d.PressureStats.M {
+		if !live[id] {
+			delete(d.PressureStats.M, id)
+		}
+	}
+	d.PressureStats.Unlock()
+
+	d.ContainerChanges.clean(live)
+	d.MemorySamples.clean(live)
+	d.CrashLoops.clean(live)
+
+	alerts := []common.MapStr{}
+	for _, state := range d.Rules.clean(live) {
+		alerts = append(alerts, d.buildAlertEvent(time.Now(), state.event, state.rule, state.value, state))
+	}
+	return alerts
+}
+
+func (d *EventGenerator) buildStats(stats *docker.Stats) calculator.BlkioData {
+	var data = calculator.BlkioData{Time: stats.Read, Reads: 0, Writes: 0, Totals: 0, Devices: map[string]calculator.BlkioData{}}
+	d.addBlkioEntries(&data, stats.BlkioStats.IOServicedRecursive, func(b *calculator.BlkioData, op string) *uint64 {
+		return selectBlkioOp(op, &b.Reads, &b.Writes, &b.Totals)
+	})
+	d.addBlkioEntries(&data, stats.BlkioStats.IOServiceBytesRecursive, func(b *calculator.BlkioData, op string) *uint64 {
+		return selectBlkioOp(op, &b.ReadBytes, &b.WriteBytes, &b.TotalBytes)
+	})
+	d.addBlkioEntries(&data, stats.BlkioStats.IOServiceTimeRecursive, func(b *calculator.BlkioData, op string) *uint64 {
+		return selectBlkioOp(op, nil, nil, &b.ServiceTime)
+	})
+	d.addBlkioEntries(&data, stats.BlkioStats.IOWaitTimeRecursive, func(b *calculator.BlkioData, op string) *uint64 {
+		return selectBlkioOp(op, nil, nil, &b.WaitTime)
+	})
+	d.addBlkioEntries(&data, stats.BlkioStats.IOQueueRecursive, func(b *calculator.BlkioData, op string) *uint64 {
+		return selectBlkioOp(op, nil, nil, &b.Queued)
+	})
+	d.addBlkioEntries(&data, stats.BlkioStats.IOMergedRecursive, func(b *calculator.BlkioData, op string) *uint64 {
+		return selectBlkioOp(op, nil, nil, &b.Merged)
+	})
+	// sectors entries do not have any operation
+	d.addBlkioEntries(&data, stats.BlkioStats.SectorsRecursive, func(b *calculator.BlkioData, op string) *uint64 {
+		return &b.Sectors
+	})
+	return data
 }
 
-func (d *EventGenerator) buildStats(time time.Time, entry []docker.BlkioStatsEntry) calculator.BlkioData {
-	var stats = calculator.BlkioData{Time: time, Reads: 0, Writes: 0, Totals: 0}
-	for _, s := range entry {
-		if s.Op == "Read" {
-			stats.Reads += s.Value
-		} else if s.Op == "Write" {
-			stats.Writes += s.Value
-		} else if s.Op == "Total" {
-			stats.Totals += s.Value
+// addBlkioEntries sums entries values into the counter returned by field, both for the container and for each device
+func (d *EventGenerator) addBlkioEntries(data *calculator.BlkioData, entries []docker.BlkioStatsEntry, field func(*calculator.BlkioData, string) *uint64) {
+	for _, entry := range entries {
+		id := d.deviceId(entry)
+		device := data.Devices[id]
+		device.Time = data.Time
+		if counter := field(data, entry.Op); counter != nil {
+			*counter += entry.Value
+			*field(&device, entry.Op) += entry.Value
 		}
+		data.Devices[id] = device
+	}
+}
+
+func selectBlkioOp(op string, read *uint64, write *uint64, total *uint64) *uint64 {
+	switch op {
+	case "Read":
+		return read
+	case "Write":
+		return write
+	case "Total":
+		return total
 	}
-	return stats
+	return nil
+}
+
+func (d *EventGenerator) deviceId(entry docker.BlkioStatsEntry) string {
+	return strconv.FormatUint(entry.Major, 10) + ":" + strconv.FormatUint(entry.Minor, 10)
 }
 
 func (d *EventGenerator) extractContainerName(names []string) string {
@@ -5,7 +5,10 @@ import (
 	"github.com/fsouza/go-dockerclient"
 	"github.com/ingensi/dockbeat/calculator"
 	"github.com/ingensi/dockbeat/calculator/mocks"
+	"github.com/ingensi/dockbeat/cgroup"
+	"github.com/ingensi/dockbeat/procfs"
 	"github.com/stretchr/testify/assert"
+	"net"
 	"testing"
 	"time"
 )
@@ -132,10 +135,11 @@ func TestEventGeneratorGetNetworksEventFirstPass(t *testing.T) {
 	expectedEvents := []common.MapStr{}
 	expectedEvents = append(expectedEvents,
 		common.MapStr{
-			"@timestamp":    common.Time(newTimestamp),
-			"type":          "net",
-			"containerID":   container.ID,
-			"containerName": "name1",
+			"@timestamp":     common.Time(newTimestamp),
+			"type":           "net",
+			"containerID":    container.ID,
+			"containerName":  "name1",
+			"containerImage": "container_image",
 			"containerLabels": []common.MapStr{
 				{
 					"key":   "label1",
@@ -149,6 +153,14 @@ func TestEventGeneratorGetNetworksEventFirstPass(t *testing.T) {
 			"dockerSocket": &socket,
 			"net": common.MapStr{
 				"name":         "eth0",
+				"rxBytes":      newNetworkData["eth0"].RxBytes,
+				"rxDropped":    newNetworkData["eth0"].RxDropped,
+				"rxErrors":     newNetworkData["eth0"].RxErrors,
+				"rxPackets":    newNetworkData["eth0"].RxPackets,
+				"txBytes":      newNetworkData["eth0"].TxBytes,
+				"txDropped":    newNetworkData["eth0"].TxDropped,
+				"txErrors":     newNetworkData["eth0"].TxErrors,
+				"txPackets":    newNetworkData["eth0"].TxPackets,
 				"rxBytes_ps":   mockedNetworkCalculatorEth0.GetRxBytesPerSecond(),
 				"rxDropped_ps": mockedNetworkCalculatorEth0.GetRxDroppedPerSecond(),
 				"rxErrors_ps":  mockedNetworkCalculatorEth0.GetRxErrorsPerSecond(),
@@ -157,12 +169,14 @@ func TestEventGeneratorGetNetworksEventFirstPass(t *testing.T) {
 				"txDropped_ps": mockedNetworkCalculatorEth0.GetTxDroppedPerSecond(),
 				"txErrors_ps":  mockedNetworkCalculatorEth0.GetTxErrorsPerSecond(),
 				"txPackets_ps": mockedNetworkCalculatorEth0.GetTxPacketsPerSecond(),
+				"counterReset": mockedNetworkCalculatorEth0.CounterReset(),
 			}},
 		common.MapStr{
-			"@timestamp":    common.Time(newTimestamp),
-			"type":          "net",
-			"containerID":   container.ID,
-			"containerName": "name1",
+			"@timestamp":     common.Time(newTimestamp),
+			"type":           "net",
+			"containerID":    container.ID,
+			"containerName":  "name1",
+			"containerImage": "container_image",
 			"containerLabels": []common.MapStr{
 				{
 					"key":   "label1",
@@ -176,6 +190,14 @@ func TestEventGeneratorGetNetworksEventFirstPass(t *testing.T) {
 			"dockerSocket": &socket,
 			"net": common.MapStr{
 				"name":         "em1",
+				"rxBytes":      newNetworkData["em1"].RxBytes,
+				"rxDropped":    newNetworkData["em1"].RxDropped,
+				"rxErrors":     newNetworkData["em1"].RxErrors,
+				"rxPackets":    newNetworkData["em1"].RxPackets,
+				"txBytes":      newNetworkData["em1"].TxBytes,
+				"txDropped":    newNetworkData["em1"].TxDropped,
+				"txErrors":     newNetworkData["em1"].TxErrors,
+				"txPackets":    newNetworkData["em1"].TxPackets,
 				"rxBytes_ps":   0,
 				"rxDropped_ps": 0,
 				"rxErrors_ps":  0,
@@ -184,10 +206,11 @@ func TestEventGeneratorGetNetworksEventFirstPass(t *testing.T) {
 				"txDropped_ps": 0,
 				"txErrors_ps":  0,
 				"txPackets_ps": 0,
+				"counterReset": false,
 			}})
 
 	// the eventGenerator to test
-	var eventGenerator = EventGenerator{&socket, EGNetworkStats{M: oldNetworkData}, EGBlkioStats{}, mockedCalculatorFactory, period}
+	var eventGenerator = EventGenerator{Socket: &socket, NetworkStats: EGNetworkStats{M: oldNetworkData}, CalculatorFactory: mockedCalculatorFactory, Period: period}
 
 	// WHEN
 	events := eventGenerator.GetNetworksEvent(&container, stats)
@@ -209,6 +232,13 @@ func TestEventGeneratorGetNetworksEventFirstPass(t *testing.T) {
 		}
 	}
 
+	// rates of new networks are float zeros, like the following rates
+	for _, event := range events {
+		if net := event["net"].(common.MapStr); net["name"] == "em1" {
+			assert.Equal(t, float64(0), net["rxBytes_ps"])
+		}
+	}
+
 	// check that new stats saved
 	assert.Equal(t, eventGenerator.NetworkStats.M[container.ID]["eth0"], newNetworkData["eth0"])
 	assert.Equal(t, eventGenerator.NetworkStats.M[container.ID]["em1"], newNetworkData["em1"])
@@ -347,10 +377,11 @@ func TestEventGeneratorGetNetworksEvent(t *testing.T) {
 	expectedEvents := []common.MapStr{}
 	expectedEvents = append(expectedEvents,
 		common.MapStr{
-			"@timestamp":    common.Time(newTimestamp),
-			"type":          "net",
-			"containerID":   container.ID,
-			"containerName": "name1",
+			"@timestamp":     common.Time(newTimestamp),
+			"type":           "net",
+			"containerID":    container.ID,
+			"containerName":  "name1",
+			"containerImage": "container_image",
 			"containerLabels": []common.MapStr{
 				{
 					"key":   "label1",
@@ -364,6 +395,14 @@ func TestEventGeneratorGetNetworksEvent(t *testing.T) {
 			"dockerSocket": &socket,
 			"net": common.MapStr{
 				"name":         "eth0",
+				"rxBytes":      newNetworkData["eth0"].RxBytes,
+				"rxDropped":    newNetworkData["eth0"].RxDropped,
+				"rxErrors":     newNetworkData["eth0"].RxErrors,
+				"rxPackets":    newNetworkData["eth0"].RxPackets,
+				"txBytes":      newNetworkData["eth0"].TxBytes,
+				"txDropped":    newNetworkData["eth0"].TxDropped,
+				"txErrors":     newNetworkData["eth0"].TxErrors,
+				"txPackets":    newNetworkData["eth0"].TxPackets,
 				"rxBytes_ps":   mockedNetworkCalculatorEth0.GetRxBytesPerSecond(),
 				"rxDropped_ps": mockedNetworkCalculatorEth0.GetRxDroppedPerSecond(),
 				"rxErrors_ps":  mockedNetworkCalculatorEth0.GetRxErrorsPerSecond(),
@@ -372,12 +411,14 @@ func TestEventGeneratorGetNetworksEvent(t *testing.T) {
 				"txDropped_ps": mockedNetworkCalculatorEth0.GetTxDroppedPerSecond(),
 				"txErrors_ps":  mockedNetworkCalculatorEth0.GetTxErrorsPerSecond(),
 				"txPackets_ps": mockedNetworkCalculatorEth0.GetTxPacketsPerSecond(),
+				"counterReset": mockedNetworkCalculatorEth0.CounterReset(),
 			}},
 		common.MapStr{
-			"@timestamp":    common.Time(newTimestamp),
-			"type":          "net",
-			"containerID":   container.ID,
-			"containerName": "name1",
+			"@timestamp":     common.Time(newTimestamp),
+			"type":           "net",
+			"containerID":    container.ID,
+			"containerName":  "name1",
+			"containerImage": "container_image",
 			"containerLabels": []common.MapStr{
 				{
 					"key":   "label1",
@@ -391,6 +432,14 @@ func TestEventGeneratorGetNetworksEvent(t *testing.T) {
 			"dockerSocket": &socket,
 			"net": common.MapStr{
 				"name":         "em1",
+				"rxBytes":      newNetworkData["em1"].RxBytes,
+				"rxDropped":    newNetworkData["em1"].RxDropped,
+				"rxErrors":     newNetworkData["em1"].RxErrors,
+				"rxPackets":    newNetworkData["em1"].RxPackets,
+				"txBytes":      newNetworkData["em1"].TxBytes,
+				"txDropped":    newNetworkData["em1"].TxDropped,
+				"txErrors":     newNetworkData["em1"].TxErrors,
+				"txPackets":    newNetworkData["em1"].TxPackets,
 				"rxBytes_ps":   mockedNetworkCalculatorEm1.GetRxBytesPerSecond(),
 				"rxDropped_ps": mockedNetworkCalculatorEm1.GetRxDroppedPerSecond(),
 				"rxErrors_ps":  mockedNetworkCalculatorEm1.GetRxErrorsPerSecond(),
@@ -399,10 +448,11 @@ func TestEventGeneratorGetNetworksEvent(t *testing.T) {
 				"txDropped_ps": mockedNetworkCalculatorEm1.GetTxDroppedPerSecond(),
 				"txErrors_ps":  mockedNetworkCalculatorEm1.GetTxErrorsPerSecond(),
 				"txPackets_ps": mockedNetworkCalculatorEm1.GetTxPacketsPerSecond(),
+				"counterReset": mockedNetworkCalculatorEm1.CounterReset(),
 			}})
 
 	// the eventGenerator to test
-	var eventGenerator = EventGenerator{&socket, EGNetworkStats{M: oldNetworkData}, EGBlkioStats{}, mockedCalculatorFactory, period}
+	var eventGenerator = EventGenerator{Socket: &socket, NetworkStats: EGNetworkStats{M: oldNetworkData}, CalculatorFactory: mockedCalculatorFactory, Period: period}
 
 	// WHEN
 	events := eventGenerator.GetNetworksEvent(&container, stats)
@@ -542,10 +592,11 @@ func TestEventGeneratorGetNetworksEventCleanSavedEvents(t *testing.T) {
 	expectedEvents := []common.MapStr{}
 	expectedEvents = append(expectedEvents,
 		common.MapStr{
-			"@timestamp":    common.Time(newTimestamp),
-			"type":          "net",
-			"containerID":   container.ID,
-			"containerName": "name1",
+			"@timestamp":     common.Time(newTimestamp),
+			"type":           "net",
+			"containerID":    container.ID,
+			"containerName":  "name1",
+			"containerImage": "container_image",
 			"containerLabels": []common.MapStr{
 				{
 					"key":   "label1",
@@ -559,6 +610,14 @@ func TestEventGeneratorGetNetworksEventCleanSavedEvents(t *testing.T) {
 			"dockerSocket": &socket,
 			"net": common.MapStr{
 				"name":         "eth0",
+				"rxBytes":      newNetworkData["eth0"].RxBytes,
+				"rxDropped":    newNetworkData["eth0"].RxDropped,
+				"rxErrors":     newNetworkData["eth0"].RxErrors,
+				"rxPackets":    newNetworkData["eth0"].RxPackets,
+				"txBytes":      newNetworkData["eth0"].TxBytes,
+				"txDropped":    newNetworkData["eth0"].TxDropped,
+				"txErrors":     newNetworkData["eth0"].TxErrors,
+				"txPackets":    newNetworkData["eth0"].TxPackets,
 				"rxBytes_ps":   mockedNetworkCalculatorEth0.GetRxBytesPerSecond(),
 				"rxDropped_ps": mockedNetworkCalculatorEth0.GetRxDroppedPerSecond(),
 				"rxErrors_ps":  mockedNetworkCalculatorEth0.GetRxErrorsPerSecond(),
@@ -567,10 +626,11 @@ func TestEventGeneratorGetNetworksEventCleanSavedEvents(t *testing.T) {
 				"txDropped_ps": mockedNetworkCalculatorEth0.GetTxDroppedPerSecond(),
 				"txErrors_ps":  mockedNetworkCalculatorEth0.GetTxErrorsPerSecond(),
 				"txPackets_ps": mockedNetworkCalculatorEth0.GetTxPacketsPerSecond(),
+				"counterReset": mockedNetworkCalculatorEth0.CounterReset(),
 			}})
 
 	// the eventGenerator to test
-	var eventGenerator = EventGenerator{&socket, EGNetworkStats{M: oldNetworkData}, EGBlkioStats{}, mockedCalculatorFactory, period}
+	var eventGenerator = EventGenerator{Socket: &socket, NetworkStats: EGNetworkStats{M: oldNetworkData}, CalculatorFactory: mockedCalculatorFactory, Period: period}
 
 	// WHEN
 	events := eventGenerator.GetNetworksEvent(&container, stats)
@@ -626,14 +686,15 @@ func TestEventGeneratorGetContainerEvent(t *testing.T) {
 	timestamp := time.Now()
 	var stats = new(docker.Stats)
 	stats.Read = timestamp
-	var eventGenerator = EventGenerator{&socket, EGNetworkStats{}, EGBlkioStats{}, calculator.CalculatorFactoryImpl{}, time.Second}
+	var eventGenerator = EventGenerator{Socket: &socket, CalculatorFactory: calculator.CalculatorFactoryImpl{}, Period: time.Second}
 
 	// expected output
 	expectedEvent := common.MapStr{
-		"@timestamp":    common.Time(timestamp),
-		"type":          "container",
-		"containerID":   container.ID,
-		"containerName": "name1",
+		"@timestamp":     common.Time(timestamp),
+		"type":           "container",
+		"containerID":    container.ID,
+		"containerName":  "name1",
+		"containerImage": "container_image",
 		"containerLabels": []common.MapStr{
 			{
 				"key":   "label1",
@@ -700,14 +761,15 @@ func TestEventGeneratorGetContainerEventWithNoPorts(t *testing.T) {
 	timestamp := time.Now()
 	var stats = new(docker.Stats)
 	stats.Read = timestamp
-	var eventGenerator = &EventGenerator{&socket, EGNetworkStats{}, EGBlkioStats{}, calculator.CalculatorFactoryImpl{}, time.Second}
+	var eventGenerator = &EventGenerator{Socket: &socket, CalculatorFactory: calculator.CalculatorFactoryImpl{}, Period: time.Second}
 
 	// expected output
 	expectedEvent := common.MapStr{
-		"@timestamp":    common.Time(timestamp),
-		"type":          "container",
-		"containerID":   container.ID,
-		"containerName": "name1",
+		"@timestamp":     common.Time(timestamp),
+		"type":           "container",
+		"containerID":    container.ID,
+		"containerName":  "name1",
+		"containerImage": "container_image",
 		"containerLabels": []common.MapStr{
 			{
 				"key":   "label1",
@@ -795,13 +857,16 @@ func TestEventGeneratorGetCpuEvent(t *testing.T) {
 		TotalUsage:        cpuStats.CPUUsage.TotalUsage,
 		UsageInKernelmode: cpuStats.CPUUsage.UsageInKernelmode,
 		UsageInUsermode:   cpuStats.CPUUsage.UsageInUsermode,
+		Time:              stats.Read,
 	}
 
+	// without a previous tick, the precpu stats are sampled one second before
 	preCPUData := calculator.CPUData{
 		PerCpuUsage:       preCPUStats.CPUUsage.PercpuUsage,
 		TotalUsage:        preCPUStats.CPUUsage.TotalUsage,
 		UsageInKernelmode: preCPUStats.CPUUsage.UsageInKernelmode,
 		UsageInUsermode:   preCPUStats.CPUUsage.UsageInUsermode,
+		Time:              stats.Read.Add(-time.Second),
 	}
 
 	// second - instantiate mock
@@ -812,10 +877,11 @@ func TestEventGeneratorGetCpuEvent(t *testing.T) {
 
 	// expected events
 	expectedEvent := common.MapStr{
-		"@timestamp":    common.Time(stats.Read),
-		"type":          "cpu",
-		"containerID":   container.ID,
-		"containerName": "name1",
+		"@timestamp":     common.Time(stats.Read),
+		"type":           "cpu",
+		"containerID":    container.ID,
+		"containerName":  "name1",
+		"containerImage": "container_image",
 		"containerLabels": []common.MapStr{
 			{
 				"key":   "label1",
@@ -832,11 +898,17 @@ func TestEventGeneratorGetCpuEvent(t *testing.T) {
 			"totalUsage":        mockedCPUCalculator.TotalUsage(),
 			"usageInKernelmode": mockedCPUCalculator.UsageInKernelmode(),
 			"usageInUsermode":   mockedCPUCalculator.UsageInUsermode(),
+			"counterReset":      mockedCPUCalculator.CounterReset(),
 		},
 	}
 
 	// the eventGenerator to test
-	var eventGenerator = EventGenerator{&socket, EGNetworkStats{}, EGBlkioStats{}, mockedCalculatorFactory, time.Second}
+	var eventGenerator = EventGenerator{
+		Socket:            &socket,
+		CpuStats:          EGCpuStats{M: map[string]calculator.CPUData{}},
+		CalculatorFactory: mockedCalculatorFactory,
+		Period:            time.Second,
+	}
 
 	// WHEN
 	event := eventGenerator.GetCpuEvent(&container, stats)
@@ -844,6 +916,48 @@ func TestEventGeneratorGetCpuEvent(t *testing.T) {
 	// THEN
 	// check returned events
 	assert.True(t, equalEvent(expectedEvent, event))
+	// the cpu data is saved for the next tick
+	assert.Equal(t, cpuData, eventGenerator.CpuStats.M[containerId])
+}
+
+/*
+TestEventGeneratorGetCpuEventPreviousTick checks that the cpu data saved at the previous tick is used instead of the
+precpu stats, so that the usage is computed over the measured interval.
+*/
+func TestEventGeneratorGetCpuEventPreviousTick(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := docker.APIContainers{ID: "container_id", Names: []string{"/name1"}}
+	stats := new(docker.Stats)
+	stats.Read = time.Now()
+	stats.PreCPUStats = getCPUStats(1)
+	stats.CPUStats = getCPUStats(2)
+
+	oldCPUData := calculator.CPUData{TotalUsage: 1, Time: stats.Read.Add(-3 * time.Second)}
+	cpuData := calculator.CPUData{
+		PerCpuUsage:       stats.CPUStats.CPUUsage.PercpuUsage,
+		TotalUsage:        stats.CPUStats.CPUUsage.TotalUsage,
+		UsageInKernelmode: stats.CPUStats.CPUUsage.UsageInKernelmode,
+		UsageInUsermode:   stats.CPUStats.CPUUsage.UsageInUsermode,
+		Time:              stats.Read,
+	}
+	mockedCalculatorFactory := new(mocks.CalculatorFactory)
+	mockedCalculatorFactory.On("NewCPUCalculator", oldCPUData, cpuData).Return(getMockedCPUCalculator(1.0))
+
+	var eventGenerator = EventGenerator{
+		Socket:            &socket,
+		CpuStats:          EGCpuStats{M: map[string]calculator.CPUData{"container_id": oldCPUData}},
+		CalculatorFactory: mockedCalculatorFactory,
+		Period:            3 * time.Second,
+	}
+
+	// WHEN
+	event := eventGenerator.GetCpuEvent(&container, stats)
+
+	// THEN
+	mockedCalculatorFactory.AssertExpectations(t)
+	assert.Equal(t, "cpu", event["type"])
+	assert.Equal(t, cpuData, eventGenerator.CpuStats.M["container_id"])
 }
 
 // MEMORY EVENT GENERATION
@@ -882,10 +996,11 @@ func TestEventGeneratorGetMemoryEvent(t *testing.T) {
 
 	// expected events
 	expectedEvent := common.MapStr{
-		"@timestamp":    common.Time(stats.Read),
-		"type":          "memory",
-		"containerID":   container.ID,
-		"containerName": "name1",
+		"@timestamp":     common.Time(stats.Read),
+		"type":           "memory",
+		"containerID":    container.ID,
+		"containerName":  "name1",
+		"containerImage": "container_image",
 		"containerLabels": []common.MapStr{
 			{
 				"key":   "label1",
@@ -909,7 +1024,7 @@ func TestEventGeneratorGetMemoryEvent(t *testing.T) {
 	}
 
 	// the eventGenerator to test
-	var eventGenerator = EventGenerator{&socket, EGNetworkStats{}, EGBlkioStats{}, nil, time.Second}
+	var eventGenerator = EventGenerator{Socket: &socket, Period: time.Second}
 
 	// WHEN
 	event := eventGenerator.GetMemoryEvent(&container, &stats)
@@ -919,6 +1034,168 @@ func TestEventGeneratorGetMemoryEvent(t *testing.T) {
 	assert.True(t, equalEvent(expectedEvent, event))
 }
 
+func TestEventGeneratorAddMemoryEvents(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := docker.APIContainers{ID: "container_id", Names: []string{"/name1"}}
+	var stats = getMemoryStats(time.Now(), 1)
+	var eventGenerator = EventGenerator{Socket: &socket, Period: time.Second}
+	event := eventGenerator.GetMemoryEvent(&container, &stats)
+
+	// WHEN
+	// unknown counters are ignored
+	eventGenerator.AddMemoryEvents(event, map[string]uint64{"high": 4, "max": 2, "oom": 1, "oom_kill": 1, "unknown": 8})
+
+	// THEN
+	assert.Equal(t, common.MapStr{
+		"low":      uint64(0),
+		"high":     uint64(4),
+		"max":      uint64(2),
+		"oom":      uint64(1),
+		"oom_kill": uint64(1),
+	}, event["memory"].(common.MapStr)["events"])
+}
+
+func TestEventGeneratorAddMemoryLimit(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := docker.APIContainers{ID: "container_id", Names: []string{"/name1"}}
+	var stats = getMemoryStats(time.Now(), 1)
+	limited := &docker.Container{HostConfig: &docker.HostConfig{Memory: 536870912}}
+	unlimited := &docker.Container{HostConfig: &docker.HostConfig{Memory: 0}}
+	var eventGenerator = EventGenerator{Socket: &socket, Period: time.Second}
+	limitedEvent := eventGenerator.GetMemoryEvent(&container, &stats)
+	unlimitedEvent := eventGenerator.GetMemoryEvent(&container, &stats)
+	unknownHostEvent := eventGenerator.GetMemoryEvent(&container, &stats)
+
+	// WHEN
+	eventGenerator.AddMemoryLimit(limitedEvent, &stats, limited, 4*int64(stats.MemoryStats.Usage))
+	eventGenerator.AddMemoryLimit(unlimitedEvent, &stats, unlimited, 4*int64(stats.MemoryStats.Usage))
+	eventGenerator.AddMemoryLimit(unknownHostEvent, &stats, unlimited, 0)
+
+	// THEN
+	// the host usage is only computed for containers without limit
+	assert.Equal(t, true, limitedEvent["memory"].(common.MapStr)["limited"])
+	assert.Nil(t, limitedEvent["memory"].(common.MapStr)["hostUsage_p"])
+	assert.Equal(t, false, unlimitedEvent["memory"].(common.MapStr)["limited"])
+	assert.Equal(t, 0.25, unlimitedEvent["memory"].(common.MapStr)["hostUsage_p"])
+	assert.Equal(t, false, unknownHostEvent["memory"].(common.MapStr)["limited"])
+	assert.Nil(t, unknownHostEvent["memory"].(common.MapStr)["hostUsage_p"])
+}
+
+// PIDS EVENT GENERATION
+
+/*
+TestEventGeneratorGetPidsEvent simulates the case when a pids event is generated for a container with a pids limit
+
+It checks the event format, according to the incoming pids stats and the container limit.
+*/
+func TestEventGeneratorGetPidsEvent(t *testing.T) {
+	// GIVEN
+	// docker socket
+	socket := "unix:///some/docker/socket"
+
+	// a container with a pids limit
+	labels := map[string]string{}
+	labels["label1"] = "value1"
+	container := docker.APIContainers{
+		ID:     "container_id",
+		Names:  []string{"/name1"},
+		Labels: labels,
+	}
+	info := docker.Container{
+		ID:         "container_id",
+		HostConfig: &docker.HostConfig{PidsLimit: 200},
+	}
+
+	// main stats object
+	var stats = new(docker.Stats)
+	stats.Read = time.Now()
+	stats.PidsStats.Current = 50
+
+	// expected event
+	expectedEvent := common.MapStr{
+		"@timestamp":     common.Time(stats.Read),
+		"type":           "pids",
+		"containerID":    container.ID,
+		"containerName":  "name1",
+		"containerImage": "",
+		"containerLabels": []common.MapStr{
+			{
+				"key":   "label1",
+				"value": "value1",
+			},
+		},
+		"dockerSocket": &socket,
+		"pids": common.MapStr{
+			"current": uint64(50),
+			"limit":   int64(200),
+			"usage_p": float64(0.25),
+		},
+	}
+
+	// the eventGenerator to test
+	var eventGenerator = EventGenerator{Socket: &socket, Period: time.Second}
+
+	// WHEN
+	event := eventGenerator.GetPidsEvent(&container, stats, &info)
+
+	// THEN
+	// check returned event
+	assert.True(t, equalEvent(expectedEvent, event))
+}
+
+/*
+TestEventGeneratorGetPidsEventUnlimited simulates the case when a pids event is generated for a container without pids limit
+
+It checks that no usage percentage is generated.
+*/
+func TestEventGeneratorGetPidsEventUnlimited(t *testing.T) {
+	// GIVEN
+	// docker socket
+	socket := "unix:///some/docker/socket"
+
+	// a container without pids limit
+	container := docker.APIContainers{
+		ID:    "container_id",
+		Names: []string{"/name1"},
+	}
+	info := docker.Container{
+		ID:         "container_id",
+		HostConfig: &docker.HostConfig{PidsLimit: -1},
+	}
+
+	// main stats object
+	var stats = new(docker.Stats)
+	stats.Read = time.Now()
+	stats.PidsStats.Current = 50
+
+	// expected event
+	expectedEvent := common.MapStr{
+		"@timestamp":      common.Time(stats.Read),
+		"type":            "pids",
+		"containerID":     container.ID,
+		"containerName":   "name1",
+		"containerImage":  "",
+		"containerLabels": []common.MapStr{},
+		"dockerSocket":    &socket,
+		"pids": common.MapStr{
+			"current": uint64(50),
+			"limit":   int64(0),
+		},
+	}
+
+	// the eventGenerator to test
+	var eventGenerator = EventGenerator{Socket: &socket, Period: time.Second}
+
+	// WHEN
+	event := eventGenerator.GetPidsEvent(&container, stats, &info)
+
+	// THEN
+	// check returned event
+	assert.True(t, equalEvent(expectedEvent, event))
+}
+
 // BLKIO EVENT GENERATION
 
 /*
@@ -967,22 +1244,18 @@ func TestEventGeneratorGetBlkioEventFirstPass(t *testing.T) {
 
 	// mocking calculators
 	// first - generate expected calls (NetworkStats to NetworkData conversion)
-	newBlkioData := calculator.BlkioData{
-		Time:   newTimestamp,
-		Reads:  10,
-		Writes: 20,
-		Totals: 30,
-	}
+	newBlkioData := getBlkioData(newTimestamp, 10, 20, 30)
 
 	// second - instantiate mock
 	// calculator will no be called, it will generate zero-values event
 
 	// expected events
 	expectedEvent := common.MapStr{
-		"@timestamp":    common.Time(stats.Read),
-		"type":          "blkio",
-		"containerID":   container.ID,
-		"containerName": "name1",
+		"@timestamp":     common.Time(stats.Read),
+		"type":           "blkio",
+		"containerID":    container.ID,
+		"containerName":  "name1",
+		"containerImage": "container_image",
 		"containerLabels": []common.MapStr{
 			{
 				"key":   "label1",
@@ -995,14 +1268,30 @@ func TestEventGeneratorGetBlkioEventFirstPass(t *testing.T) {
 		},
 		"dockerSocket": &socket,
 		"blkio": common.MapStr{
-			"read_ps":  float64(0),
-			"write_ps": float64(0),
-			"total_ps": float64(0),
+			"read":              uint64(10),
+			"write":             uint64(20),
+			"total":             uint64(30),
+			"readBytes":         uint64(10 * 512),
+			"writeBytes":        uint64(20 * 512),
+			"totalBytes":        uint64(30 * 512),
+			"read_ps":           float64(0),
+			"write_ps":          float64(0),
+			"total_ps":          float64(0),
+			"readBytes_ps":      float64(0),
+			"writeBytes_ps":     float64(0),
+			"totalBytes_ps":     float64(0),
+			"avgServiceTime_ms": float64(0),
+			"avgWaitTime_ms":    float64(0),
+			"queued":            uint64(3),
+			"merged_ps":         float64(0),
+			"sectors_ps":        float64(0),
+			"counterReset":      false,
+			"devices":           getBlkioEventDevice(nil),
 		},
 	}
 
 	// the eventGenerator to test
-	var eventGenerator = EventGenerator{&socket, EGNetworkStats{}, EGBlkioStats{M: oldBlkioData}, nil, time.Second}
+	var eventGenerator = EventGenerator{Socket: &socket, BlkioStats: EGBlkioStats{M: oldBlkioData}, BlockDevices: EGBlockDevices{M: map[string]string{"8:0": "sda"}}, Period: time.Second}
 
 	// WHEN
 	event := eventGenerator.GetBlkioEvent(&container, &stats)
@@ -1059,33 +1348,26 @@ func TestEventGeneratorGetBlkioEvent(t *testing.T) {
 
 	// saved network status
 	oldBlkioData := map[string]calculator.BlkioData{}
-	oldBlkioData[containerId] = calculator.BlkioData{
-		Time:   oldTimestamp,
-		Reads:  1,
-		Writes: 2,
-		Totals: 3,
-	}
+	oldBlkioData[containerId] = getBlkioData(oldTimestamp, 1, 2, 3)
 
 	// mocking calculators
 	// first - generate expected calls (NetworkStats to NetworkData conversion)
-	newBlkioData := calculator.BlkioData{
-		Time:   newTimestamp,
-		Reads:  10,
-		Writes: 20,
-		Totals: 30,
-	}
+	newBlkioData := getBlkioData(newTimestamp, 10, 20, 30)
 
 	// second - instantiate mock
 	mockedCalculatorFactory := new(mocks.CalculatorFactory)
 	mockedBlkioCalculator := getMockedBlkioCalculator(1)
+	mockedDeviceBlkioCalculator := getMockedBlkioCalculator(2)
 	mockedCalculatorFactory.On("NewBlkioCalculator", oldBlkioData[containerId], newBlkioData).Return(mockedBlkioCalculator)
+	mockedCalculatorFactory.On("NewBlkioCalculator", oldBlkioData[containerId].Devices["8:0"], newBlkioData.Devices["8:0"]).Return(mockedDeviceBlkioCalculator)
 
 	// expected events
 	expectedEvent := common.MapStr{
-		"@timestamp":    common.Time(stats.Read),
-		"type":          "blkio",
-		"containerID":   container.ID,
-		"containerName": "name1",
+		"@timestamp":     common.Time(stats.Read),
+		"type":           "blkio",
+		"containerID":    container.ID,
+		"containerName":  "name1",
+		"containerImage": "container_image",
 		"containerLabels": []common.MapStr{
 			{
 				"key":   "label1",
@@ -1098,14 +1380,30 @@ func TestEventGeneratorGetBlkioEvent(t *testing.T) {
 		},
 		"dockerSocket": &socket,
 		"blkio": common.MapStr{
-			"read_ps":  mockedBlkioCalculator.GetReadPs(),
-			"write_ps": mockedBlkioCalculator.GetWritePs(),
-			"total_ps": mockedBlkioCalculator.GetTotalPs(),
+			"read":              uint64(10),
+			"write":             uint64(20),
+			"total":             uint64(30),
+			"readBytes":         uint64(10 * 512),
+			"writeBytes":        uint64(20 * 512),
+			"totalBytes":        uint64(30 * 512),
+			"read_ps":           mockedBlkioCalculator.GetReadPs(),
+			"write_ps":          mockedBlkioCalculator.GetWritePs(),
+			"total_ps":          mockedBlkioCalculator.GetTotalPs(),
+			"readBytes_ps":      mockedBlkioCalculator.GetReadBytesPs(),
+			"writeBytes_ps":     mockedBlkioCalculator.GetWriteBytesPs(),
+			"totalBytes_ps":     mockedBlkioCalculator.GetTotalBytesPs(),
+			"avgServiceTime_ms": mockedBlkioCalculator.GetAvgServiceTime(),
+			"avgWaitTime_ms":    mockedBlkioCalculator.GetAvgWaitTime(),
+			"queued":            uint64(3),
+			"merged_ps":         mockedBlkioCalculator.GetMergedPs(),
+			"sectors_ps":        mockedBlkioCalculator.GetSectorsPs(),
+			"counterReset":      mockedBlkioCalculator.CounterReset(),
+			"devices":           getBlkioEventDevice(mockedDeviceBlkioCalculator),
 		},
 	}
 
 	// the eventGenerator to test
-	var eventGenerator = EventGenerator{&socket, EGNetworkStats{}, EGBlkioStats{M: oldBlkioData}, mockedCalculatorFactory, time.Second}
+	var eventGenerator = EventGenerator{Socket: &socket, BlkioStats: EGBlkioStats{M: oldBlkioData}, BlockDevices: EGBlockDevices{M: map[string]string{"8:0": "sda"}}, CalculatorFactory: mockedCalculatorFactory, Period: time.Second}
 
 	// WHEN
 	event := eventGenerator.GetBlkioEvent(&container, &stats)
@@ -1164,40 +1462,28 @@ func TestEventGeneratorGetBlkioEventCleanSavedEvents(t *testing.T) {
 
 	// saved blkio stats
 	oldBlkioData := map[string]calculator.BlkioData{}
-	oldBlkioData[containerId] = calculator.BlkioData{
-		Time:   oldTimestamp,
-		Reads:  1,
-		Writes: 2,
-		Totals: 3,
-	}
+	oldBlkioData[containerId] = getBlkioData(oldTimestamp, 1, 2, 3)
 	// another container has a very old blkio stats
-	oldBlkioData[anotherContainerId] = calculator.BlkioData{
-		Time:   veryOldTimestamp,
-		Reads:  4,
-		Writes: 5,
-		Totals: 6,
-	}
+	oldBlkioData[anotherContainerId] = getBlkioData(veryOldTimestamp, 4, 5, 6)
 
 	// mocking calculators
 	// first - generate expected calls (BlkioStats to BlkioData conversion)
-	newBlkioData := calculator.BlkioData{
-		Time:   newTimestamp,
-		Reads:  10,
-		Writes: 20,
-		Totals: 30,
-	}
+	newBlkioData := getBlkioData(newTimestamp, 10, 20, 30)
 
 	// second - instantiate mock
 	mockedCalculatorFactory := new(mocks.CalculatorFactory)
 	mockedBlkioCalculator := getMockedBlkioCalculator(1)
+	mockedDeviceBlkioCalculator := getMockedBlkioCalculator(2)
 	mockedCalculatorFactory.On("NewBlkioCalculator", oldBlkioData[containerId], newBlkioData).Return(mockedBlkioCalculator)
+	mockedCalculatorFactory.On("NewBlkioCalculator", oldBlkioData[containerId].Devices["8:0"], newBlkioData.Devices["8:0"]).Return(mockedDeviceBlkioCalculator)
 
 	// expected events
 	expectedEvent := common.MapStr{
-		"@timestamp":    common.Time(newTimestamp),
-		"type":          "blkio",
-		"containerID":   container.ID,
-		"containerName": "name1",
+		"@timestamp":     common.Time(newTimestamp),
+		"type":           "blkio",
+		"containerID":    container.ID,
+		"containerName":  "name1",
+		"containerImage": "container_image",
 		"containerLabels": []common.MapStr{
 			{
 				"key":   "label1",
@@ -1210,14 +1496,30 @@ func TestEventGeneratorGetBlkioEventCleanSavedEvents(t *testing.T) {
 		},
 		"dockerSocket": &socket,
 		"blkio": common.MapStr{
-			"read_ps":  mockedBlkioCalculator.GetReadPs(),
-			"write_ps": mockedBlkioCalculator.GetWritePs(),
-			"total_ps": mockedBlkioCalculator.GetTotalPs(),
+			"read":              uint64(10),
+			"write":             uint64(20),
+			"total":             uint64(30),
+			"readBytes":         uint64(10 * 512),
+			"writeBytes":        uint64(20 * 512),
+			"totalBytes":        uint64(30 * 512),
+			"read_ps":           mockedBlkioCalculator.GetReadPs(),
+			"write_ps":          mockedBlkioCalculator.GetWritePs(),
+			"total_ps":          mockedBlkioCalculator.GetTotalPs(),
+			"readBytes_ps":      mockedBlkioCalculator.GetReadBytesPs(),
+			"writeBytes_ps":     mockedBlkioCalculator.GetWriteBytesPs(),
+			"totalBytes_ps":     mockedBlkioCalculator.GetTotalBytesPs(),
+			"avgServiceTime_ms": mockedBlkioCalculator.GetAvgServiceTime(),
+			"avgWaitTime_ms":    mockedBlkioCalculator.GetAvgWaitTime(),
+			"queued":            uint64(3),
+			"merged_ps":         mockedBlkioCalculator.GetMergedPs(),
+			"sectors_ps":        mockedBlkioCalculator.GetSectorsPs(),
+			"counterReset":      mockedBlkioCalculator.CounterReset(),
+			"devices":           getBlkioEventDevice(mockedDeviceBlkioCalculator),
 		},
 	}
 
 	// the eventGenerator to test
-	var eventGenerator = EventGenerator{&socket, EGNetworkStats{}, EGBlkioStats{M: oldBlkioData}, mockedCalculatorFactory, period}
+	var eventGenerator = EventGenerator{Socket: &socket, BlkioStats: EGBlkioStats{M: oldBlkioData}, BlockDevices: EGBlockDevices{M: map[string]string{"8:0": "sda"}}, CalculatorFactory: mockedCalculatorFactory, Period: period}
 
 	// WHEN
 	event := eventGenerator.GetBlkioEvent(&container, &stats)
@@ -1262,7 +1564,7 @@ func TestEventGeneratorGetLogEvent(t *testing.T) {
 	}
 
 	// the eventGenerator to test
-	var eventGenerator = EventGenerator{&socket, EGNetworkStats{}, EGBlkioStats{}, nil, time.Second}
+	var eventGenerator = EventGenerator{Socket: &socket, Period: time.Second}
 
 	// WHEN
 	event := eventGenerator.GetLogEvent(level, message)
@@ -1327,6 +1629,7 @@ func getMockedNetworkCalculator(number float64) *mocks.NetworkCalculator {
 	mock.On("GetTxDroppedPerSecond").Return(number * 6)
 	mock.On("GetTxErrorsPerSecond").Return(number * 7)
 	mock.On("GetTxPacketsPerSecond").Return(number * 8)
+	mock.On("CounterReset").Return(false)
 	return mock
 }
 
@@ -1369,6 +1672,7 @@ func getMockedCPUCalculator(number float64) calculator.CPUCalculator {
 	mock.On("UsageInKernelmode").Return(number * 3)
 	mock.On("UsageInUsermode").Return(number * 4)
 	mock.On("CalculateLoad").Return(number * 5)
+	mock.On("CounterReset").Return(false)
 
 	return mock
 }
@@ -1429,15 +1733,81 @@ func getMemoryStats(read time.Time, number uint64) docker.Stats {
 	return testStats
 }
 
+func getBlkioData(time time.Time, reads uint64, writes uint64, total uint64) calculator.BlkioData {
+	device := calculator.BlkioData{
+		Time:        time,
+		Reads:       reads,
+		Writes:      writes,
+		Totals:      total,
+		ReadBytes:   reads * 512,
+		WriteBytes:  writes * 512,
+		TotalBytes:  total * 512,
+		ServiceTime: total * 1000000,
+		WaitTime:    total * 2000000,
+		Queued:      3,
+		Merged:      total / 2,
+		Sectors:     total,
+	}
+	data := device
+	data.Devices = map[string]calculator.BlkioData{"8:0": device}
+	return data
+}
+
+func getBlkioEventDevice(blkioCalculator calculator.BlkioCalculator) []common.MapStr {
+	device := common.MapStr{
+		"id":                "8:0",
+		"name":              "sda",
+		"read":              uint64(10),
+		"write":             uint64(20),
+		"total":             uint64(30),
+		"readBytes":         uint64(10 * 512),
+		"writeBytes":        uint64(20 * 512),
+		"totalBytes":        uint64(30 * 512),
+		"read_ps":           float64(0),
+		"write_ps":          float64(0),
+		"total_ps":          float64(0),
+		"readBytes_ps":      float64(0),
+		"writeBytes_ps":     float64(0),
+		"totalBytes_ps":     float64(0),
+		"avgServiceTime_ms": float64(0),
+		"avgWaitTime_ms":    float64(0),
+		"queued":            uint64(3),
+		"merged_ps":         float64(0),
+		"sectors_ps":        float64(0),
+	}
+	if blkioCalculator != nil {
+		device["read_ps"] = blkioCalculator.GetReadPs()
+		device["write_ps"] = blkioCalculator.GetWritePs()
+		device["total_ps"] = blkioCalculator.GetTotalPs()
+		device["readBytes_ps"] = blkioCalculator.GetReadBytesPs()
+		device["writeBytes_ps"] = blkioCalculator.GetWriteBytesPs()
+		device["totalBytes_ps"] = blkioCalculator.GetTotalBytesPs()
+		device["avgServiceTime_ms"] = blkioCalculator.GetAvgServiceTime()
+		device["avgWaitTime_ms"] = blkioCalculator.GetAvgWaitTime()
+		device["merged_ps"] = blkioCalculator.GetMergedPs()
+		device["sectors_ps"] = blkioCalculator.GetSectorsPs()
+	}
+	return []common.MapStr{device}
+}
+
 func getMockedBlkioCalculator(number float64) *mocks.BlkioCalculator {
 	mock := new(mocks.BlkioCalculator)
 	mock.On("GetReadPs").Return(number)
 	mock.On("GetWritePs").Return(number * 2)
 	mock.On("GetTotalPs").Return(number * 3)
+	mock.On("GetReadBytesPs").Return(number * 4)
+	mock.On("GetWriteBytesPs").Return(number * 5)
+	mock.On("GetTotalBytesPs").Return(number * 6)
+	mock.On("GetAvgServiceTime").Return(number * 7)
+	mock.On("GetAvgWaitTime").Return(number * 8)
+	mock.On("GetMergedPs").Return(number * 9)
+	mock.On("GetSectorsPs").Return(number * 10)
+	mock.On("CounterReset").Return(false)
 	return mock
 }
 
 func getBlkioStats(read time.Time, reads uint64, writes uint64, total uint64) docker.Stats {
+	// bytes are reported as 512 times the operation count, service and wait times as 1ms and 2ms per operation
 	type blkioStats struct {
 		IOServiceBytesRecursive []docker.BlkioStatsEntry `json:"io_service_bytes_recursive,omitempty" yaml:"io_service_bytes_recursive,omitempty"`
 		IOServicedRecursive     []docker.BlkioStatsEntry `json:"io_serviced_recursive,omitempty" yaml:"io_serviced_recursive,omitempty"`
@@ -1452,9 +1822,37 @@ func getBlkioStats(read time.Time, reads uint64, writes uint64, total uint64) do
 		Read: read,
 		BlkioStats: blkioStats{
 			IOServicedRecursive: []docker.BlkioStatsEntry{
-				{Major: 0, Minor: 0, Op: "Read", Value: reads},
-				{Major: 0, Minor: 0, Op: "Write", Value: writes},
-				{Major: 0, Minor: 0, Op: "Total", Value: total},
+				{Major: 8, Minor: 0, Op: "Read", Value: reads},
+				{Major: 8, Minor: 0, Op: "Write", Value: writes},
+				{Major: 8, Minor: 0, Op: "Total", Value: total},
+			},
+			IOServiceBytesRecursive: []docker.BlkioStatsEntry{
+				{Major: 8, Minor: 0, Op: "Read", Value: reads * 512},
+				{Major: 8, Minor: 0, Op: "Write", Value: writes * 512},
+				{Major: 8, Minor: 0, Op: "Total", Value: total * 512},
+			},
+			IOServiceTimeRecursive: []docker.BlkioStatsEntry{
+				{Major: 8, Minor: 0, Op: "Read", Value: reads * 1000000},
+				{Major: 8, Minor: 0, Op: "Write", Value: writes * 1000000},
+				{Major: 8, Minor: 0, Op: "Total", Value: total * 1000000},
+			},
+			IOWaitTimeRecursive: []docker.BlkioStatsEntry{
+				{Major: 8, Minor: 0, Op: "Read", Value: reads * 2000000},
+				{Major: 8, Minor: 0, Op: "Write", Value: writes * 2000000},
+				{Major: 8, Minor: 0, Op: "Total", Value: total * 2000000},
+			},
+			IOQueueRecursive: []docker.BlkioStatsEntry{
+				{Major: 8, Minor: 0, Op: "Read", Value: 1},
+				{Major: 8, Minor: 0, Op: "Write", Value: 2},
+				{Major: 8, Minor: 0, Op: "Total", Value: 3},
+			},
+			IOMergedRecursive: []docker.BlkioStatsEntry{
+				{Major: 8, Minor: 0, Op: "Read", Value: reads / 2},
+				{Major: 8, Minor: 0, Op: "Write", Value: writes / 2},
+				{Major: 8, Minor: 0, Op: "Total", Value: total / 2},
+			},
+			SectorsRecursive: []docker.BlkioStatsEntry{
+				{Major: 8, Minor: 0, Op: "", Value: total},
 			},
 		},
 	}
@@ -1473,3 +1871,208 @@ func equalEvent(expectedEvent common.MapStr, event common.MapStr) bool {
 	// TODO test labels
 
 }
+
+// PRESSURE EVENT GENERATION
+
+/*
+TestEventGeneratorGetPressureEvent simulates two pressure samples of a container, 10 seconds apart.
+
+It checks that the stall rate is 0 on the first event, then computed from the stall time increase.
+*/
+func TestEventGeneratorGetPressureEvent(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := docker.APIContainers{ID: "container_id", Names: []string{"/name1"}}
+	timestamp := time.Now()
+	oldPressure := getPressure(timestamp, 1000000)
+	newPressure := getPressure(timestamp.Add(10*time.Second), 3500000)
+	var eventGenerator = EventGenerator{Socket: &socket, Period: time.Second}
+
+	// WHEN
+	first := eventGenerator.GetPressureEvent(&container, &oldPressure)
+	second := eventGenerator.GetPressureEvent(&container, &newPressure)
+
+	// THEN
+	assert.Equal(t, "pressure", first["type"])
+	assert.Equal(t, common.Time(timestamp), first["@timestamp"])
+	assert.Equal(t, float64(0), first["pressure"].(common.MapStr)["io"].(common.MapStr)["some"].(common.MapStr)["stalled_p"])
+
+	// 2.5s stalled over 10s
+	assert.Equal(t, common.MapStr{
+		"avg10":     12.3,
+		"avg60":     8.2,
+		"avg300":    3.1,
+		"total":     uint64(3500000),
+		"stalled_p": 0.25,
+	}, second["pressure"].(common.MapStr)["io"].(common.MapStr)["some"])
+	assert.Equal(t, float64(0), second["pressure"].(common.MapStr)["cpu"].(common.MapStr)["some"].(common.MapStr)["stalled_p"])
+
+	// saved pressure is cleaned with the container
+	eventGenerator.CleanOldStats(map[string]bool{})
+	assert.Empty(t, eventGenerator.PressureStats.M)
+}
+
+func TestEventGeneratorCleanOldStats(t *testing.T) {
+	// GIVEN
+	// saved stats of a running container and of a removed one
+	var eventGenerator = EventGenerator{
+		NetworkStats: EGNetworkStats{M: map[string]map[string]calculator.NetworkData{"running_id": {}, "removed_id": {}}},
+		CpuStats:     EGCpuStats{M: map[string]calculator.CPUData{"running_id": {}, "removed_id": {}}},
+	}
+
+	// WHEN
+	eventGenerator.CleanOldStats(ContainerIDs([]docker.APIContainers{{ID: "running_id"}}))
+
+	// THEN
+	// only the stats of the removed container are cleaned
+	assert.Equal(t, map[string]map[string]calculator.NetworkData{"running_id": {}}, eventGenerator.NetworkStats.M)
+	assert.Equal(t, map[string]calculator.CPUData{"running_id": {}}, eventGenerator.CpuStats.M)
+}
+
+func getPressure(read time.Time, ioTotal uint64) cgroup.Pressure {
+	return cgroup.Pressure{
+		Time:   read,
+		CPU:    map[string]cgroup.PressureStats{"some": {}, "full": {}},
+		Memory: map[string]cgroup.PressureStats{"some": {}, "full": {}},
+		IO: map[string]cgroup.PressureStats{
+			"some": {Avg10: 12.3, Avg60: 8.2, Avg300: 3.1, Total: ioTotal},
+			"full": {Avg10: 10, Avg60: 7, Avg300: 2.5, Total: ioTotal / 2},
+		},
+	}
+}
+
+// TCP EVENT GENERATION
+
+func TestEventGeneratorGetTcpEvent(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := docker.APIContainers{ID: "container_id", Names: []string{"/name1"}}
+	stats := docker.Stats{Read: time.Now()}
+	sockets := []procfs.Socket{
+		{Protocol: "tcp", State: "LISTEN"},
+		{Protocol: "tcp", State: "ESTABLISHED"},
+		{Protocol: "tcp6", State: "ESTABLISHED"},
+		{Protocol: "tcp", State: "TIME_WAIT"},
+		{Protocol: "tcp", State: "CLOSE_WAIT"},
+	}
+	var eventGenerator = EventGenerator{Socket: &socket, Period: time.Second}
+
+	// WHEN
+	event := eventGenerator.GetTcpEvent(&container, &stats, sockets)
+
+	// THEN
+	assert.Equal(t, "tcp", event["type"])
+	assert.Equal(t, common.Time(stats.Read), event["@timestamp"])
+	assert.Equal(t, "container_id", event["containerID"])
+	assert.Equal(t, common.MapStr{
+		"total":       5,
+		"established": 2,
+		"syn_sent":    0,
+		"syn_recv":    0,
+		"fin_wait1":   0,
+		"fin_wait2":   0,
+		"time_wait":   1,
+		"close":       0,
+		"close_wait":  1,
+		"last_ack":    0,
+		"listen":      1,
+		"closing":     0,
+	}, event["tcp"])
+}
+
+// LISTEN EVENT GENERATION
+
+/*
+TestEventGeneratorGetListenEvent simulates a container publishing ports 80/tcp and 443/tcp and exposing 9000/tcp
+without publishing it, while it listens on 80/tcp, 8080/tcp (loopback) and 5353/udp.
+*/
+func TestEventGeneratorGetListenEvent(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := docker.APIContainers{
+		ID:    "container_id",
+		Names: []string{"/name1"},
+		Ports: []docker.APIPort{
+			{PrivatePort: 80, PublicPort: 8000, Type: "tcp", IP: "0.0.0.0"},
+			{PrivatePort: 443, PublicPort: 8443, Type: "tcp", IP: "0.0.0.0"},
+			{PrivatePort: 9000, Type: "tcp"},
+		},
+	}
+	stats := docker.Stats{Read: time.Now()}
+	sockets := []procfs.Socket{
+		{Protocol: "tcp6", LocalAddress: net.IPv6zero, LocalPort: 80, State: "LISTEN"},
+		{Protocol: "tcp", LocalAddress: net.IPv4(127, 0, 0, 1), LocalPort: 8080, State: "LISTEN"},
+		{Protocol: "udp", LocalAddress: net.IPv4zero, LocalPort: 5353, State: "CLOSE"},
+	}
+	var eventGenerator = EventGenerator{Socket: &socket, Period: time.Second}
+
+	// WHEN
+	event := eventGenerator.GetListenEvent(&container, &stats, sockets)
+
+	// THEN
+	assert.Equal(t, "listen", event["type"])
+	assert.Equal(t, common.Time(stats.Read), event["@timestamp"])
+	assert.Equal(t, common.MapStr{
+		"sockets": []common.MapStr{
+			{"protocol": "tcp", "address": "::", "port": 80, "published": true},
+			{"protocol": "tcp", "address": "127.0.0.1", "port": 8080, "published": false},
+			{"protocol": "udp", "address": "0.0.0.0", "port": 5353, "published": false},
+		},
+		"unpublished": 2,
+		"unusedPorts": []common.MapStr{
+			{"protocol": "tcp", "ip": "0.0.0.0", "privatePort": int64(443), "publicPort": int64(8443)},
+		},
+		"unused": 1,
+	}, event["listen"])
+}
+
+// FD EVENT GENERATION
+
+func TestEventGeneratorGetFdEvent(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := docker.APIContainers{ID: "container_id", Names: []string{"/name1"}}
+	stats := docker.Stats{Read: time.Now()}
+	processes := []procfs.FileDescriptors{
+		{Pid: 1, Open: 100, Limit: 0},
+		{Pid: 2, Open: 512, Limit: 1024},
+		{Pid: 3, Open: 900, Limit: 4096},
+	}
+	var eventGenerator = EventGenerator{Socket: &socket, Period: time.Second}
+
+	// WHEN
+	event := eventGenerator.GetFdEvent(&container, &stats, processes)
+
+	// THEN
+	// process 2 is the closest to its limit
+	assert.Equal(t, "fd", event["type"])
+	assert.Equal(t, common.Time(stats.Read), event["@timestamp"])
+	assert.Equal(t, common.MapStr{
+		"open":      uint64(1512),
+		"processes": 3,
+		"usage_p":   0.5,
+		"highest": common.MapStr{
+			"pid":     2,
+			"open":    uint64(512),
+			"limit":   uint64(1024),
+			"usage_p": 0.5,
+		},
+	}, event["fd"])
+}
+
+func TestEventGeneratorGetFdEventUnlimited(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := docker.APIContainers{ID: "container_id", Names: []string{"/name1"}}
+	stats := docker.Stats{Read: time.Now()}
+	processes := []procfs.FileDescriptors{{Pid: 1, Open: 10}, {Pid: 2, Open: 20}}
+	var eventGenerator = EventGenerator{Socket: &socket, Period: time.Second}
+
+	// WHEN
+	event := eventGenerator.GetFdEvent(&container, &stats, processes)
+
+	// THEN
+	// without limit, the process with the most file descriptors is reported
+	assert.Equal(t, float64(0), event["fd"].(common.MapStr)["usage_p"])
+	assert.Equal(t, 2, event["fd"].(common.MapStr)["highest"].(common.MapStr)["pid"])
+}
@@ -0,0 +1,209 @@
+package event
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// OOM event sources
+const (
+	OomSourceFailcnt = "failcnt"
+	OomSourceInspect = "inspect"
+	OomSourceDaemon  = "daemon"
+)
+
+// EGMemorySamples keeps the last memory sample of each container, to detect failcnt increments and to report
+// the memory usage just before an OOM kill.
+type EGMemorySamples struct {
+	sync.RWMutex
+	M map[string]memorySample
+}
+
+type memorySample struct {
+	time      time.Time
+	container docker.APIContainers
+	usage     uint64
+	maxUsage  uint64
+	limit     uint64
+	failcnt   uint64
+	// oomKilled and oomFinishedAt are the inspected state of the last reported kill
+	oomKilled     bool
+	oomFinishedAt time.Time
+	// daemonOom is the time of the last daemon oom event
+	daemonOom time.Time
+}
+
+// oomDaemonDelay is the maximum delay between the daemon oom event of a kill and the finish time of the container
+const oomDaemonDelay = time.Minute
+
+// newKill returns true when the inspected state is an OOM kill which was not reported yet, neither by inspect nor
+// by a daemon oom event
+func (s *memorySample) newKill(state docker.State) bool {
+	if !state.OOMKilled || s.oomKilled && state.FinishedAt.Equal(s.oomFinishedAt) {
+		return false
+	}
+	s.oomKilled, s.oomFinishedAt = true, state.FinishedAt
+	delay := state.FinishedAt.Sub(s.daemonOom)
+	return s.daemonOom.IsZero() || delay > oomDaemonDelay || delay < -oomDaemonDelay
+}
+
+/*
+GetOomEvents generates oom events for a running container, compared to its previous memory sample:
+  - when the memory failcnt increased: the usage reached the limit, the container is about to be killed
+  - when the container state says it has been killed by the OOM killer (info can be nil when not inspected), e.g.
+    while it is restarting
+
+The memory sample is then saved for the next tick, for daemon oom events and for GetExitedOomEvent.
+*/
+func (d *EventGenerator) GetOomEvents(container *docker.APIContainers, stats *docker.Stats, info *docker.Container) []common.MapStr {
+	logp.Debug("generator", "Generate oom events %v", container.ID)
+	sample := memorySample{
+		time:      stats.Read,
+		container: *container,
+		usage:     stats.MemoryStats.Usage,
+		maxUsage:  stats.MemoryStats.MaxUsage,
+		limit:     stats.MemoryStats.Limit,
+		failcnt:   stats.MemoryStats.Failcnt,
+	}
+
+	d.MemorySamples.Lock()
+	if d.MemorySamples.M == nil {
+		d.MemorySamples.M = map[string]memorySample{}
+	}
+	previous, exists := d.MemorySamples.M[container.ID]
+	sample.oomKilled, sample.oomFinishedAt, sample.daemonOom = previous.oomKilled, previous.oomFinishedAt, previous.daemonOom
+	killed := false
+	if info != nil {
+		killed = sample.newKill(info.State)
+		// the state is cleared when the container starts again
+		sample.oomKilled = sample.oomKilled && info.State.OOMKilled
+	}
+	d.MemorySamples.M[container.ID] = sample
+	d.MemorySamples.Unlock()
+
+	events := []common.MapStr{}
+	// failcnt is reset when the container restarts
+	if exists && sample.failcnt > previous.failcnt {
+		event := d.buildOomEvent(stats.Read, sample, OomSourceFailcnt)
+		event["oom"].(common.MapStr)["failcntDelta"] = sample.failcnt - previous.failcnt
+		events = append(events, event)
+	}
+	if killed {
+		// the usage before the kill is the one of the previous sample, if any
+		before := sample
+		if exists {
+			before.usage, before.maxUsage = previous.usage, previous.maxUsage
+		}
+		events = append(events, d.buildOomEvent(stats.Read, before, OomSourceInspect))
+	}
+	return events
+}
+
+// HasMemorySample returns true when the container was sampled by GetOomEvents, and not cleaned since
+func (d *EventGenerator) HasMemorySample(id string) bool {
+	d.MemorySamples.RLock()
+	defer d.MemorySamples.RUnlock()
+	_, exists := d.MemorySamples.M[id]
+	return exists
+}
+
+/*
+GetExitedOomEvent generates an oom event for a sampled container which died, when its inspected state says it has
+been killed by the OOM killer: docker only keeps the state until the container starts again. It returns nil
+otherwise, or when the kill was already reported by a daemon oom event.
+*/
+func (d *EventGenerator) GetExitedOomEvent(container *docker.APIContainers, info *docker.Container) common.MapStr {
+	d.MemorySamples.Lock()
+	sample, exists := d.MemorySamples.M[container.ID]
+	if !exists {
+		d.MemorySamples.Unlock()
+		return nil
+	}
+	killed := sample.newKill(info.State)
+	d.MemorySamples.M[container.ID] = sample
+	d.MemorySamples.Unlock()
+
+	if !killed {
+		return nil
+	}
+	logp.Debug("generator", "Generate exited oom event %v", container.ID)
+	return d.buildOomEvent(info.State.FinishedAt, sample, OomSourceInspect)
+}
+
+/*
+GetDaemonOomEvent generates an oom event from an oom event of the docker daemon, with the last memory sample of
+the container. It returns nil if the daemon event is not an oom event.
+*/
+func (d *EventGenerator) GetDaemonOomEvent(daemonEvent *docker.APIEvents) common.MapStr {
+	// API >= 1.22 events have an action and an actor, older ones a status and an id
+	id := daemonEvent.Actor.ID
+	if id == "" {
+		id = daemonEvent.ID
+	}
+	if daemonEvent.Action != "oom" && daemonEvent.Status != "oom" {
+		return nil
+	}
+	logp.Debug("generator", "Generate daemon oom event %v", id)
+
+	timestamp := time.Unix(0, daemonEvent.TimeNano)
+	if daemonEvent.TimeNano == 0 {
+		timestamp = time.Unix(daemonEvent.Time, 0)
+	}
+
+	d.MemorySamples.Lock()
+	sample, exists := d.MemorySamples.M[id]
+	if exists {
+		// the kill is not reported again by inspect
+		sample.daemonOom = timestamp
+		d.MemorySamples.M[id] = sample
+	}
+	d.MemorySamples.Unlock()
+	if !exists {
+		// the container has not been sampled yet, only its name is known
+		name, ok := daemonEvent.Actor.Attributes["name"]
+		if !ok {
+			name = id
+		}
+		sample = memorySample{container: docker.APIContainers{ID: id, Names: []string{"/" + name}}}
+	}
+	return d.buildOomEvent(timestamp, sample, OomSourceDaemon)
+}
+
+func (d *EventGenerator) buildOomEvent(timestamp time.Time, sample memorySample, source string) common.MapStr {
+	oom := common.MapStr{
+		"source":   source,
+		"usage":    sample.usage,
+		"maxUsage": sample.maxUsage,
+		"limit":    sample.limit,
+		"failcnt":  sample.failcnt,
+	}
+	if sample.limit > 0 {
+		oom["usage_p"] = float64(sample.usage) / float64(sample.limit)
+	}
+
+	return common.MapStr{
+		"@timestamp":      common.Time(timestamp),
+		"type":            "oom",
+		"containerID":     sample.container.ID,
+		"containerName":   d.extractContainerName(sample.container.Names),
+		"containerLabels": d.buildLabelArray(sample.container.Labels),
+		"containerImage":  sample.container.Image,
+		"dockerSocket":    d.Socket,
+		"oom":             oom,
+	}
+}
+
+// clean removes the memory samples of the containers which are gone
+func (s *EGMemorySamples) clean(live map[string]bool) {
+	s.Lock()
+	defer s.Unlock()
+	for id := range s.M {
+		if !live[id] {
+			delete(s.M, id)
+		}
+	}
+}
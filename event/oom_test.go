@@ -0,0 +1,213 @@
+package event
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/ingensi/dockbeat/calculator"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+/*
+TestEventGeneratorGetOomEventsFailcnt simulates a container whose memory usage reaches its limit between two ticks.
+*/
+func TestEventGeneratorGetOomEventsFailcnt(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := getOomContainer()
+	timestamp := time.Now()
+	var eventGenerator = EventGenerator{Socket: &socket}
+
+	// WHEN
+	first := eventGenerator.GetOomEvents(container, getOomStats(timestamp, 1024, 0), nil)
+	second := eventGenerator.GetOomEvents(container, getOomStats(timestamp.Add(time.Second), 2048, 3), nil)
+	third := eventGenerator.GetOomEvents(container, getOomStats(timestamp.Add(2*time.Second), 2048, 3), nil)
+
+	// THEN
+	// the first sample is only saved
+	assert.Empty(t, first)
+	assert.Empty(t, third)
+	assert.Equal(t, []common.MapStr{{
+		"@timestamp":      common.Time(timestamp.Add(time.Second)),
+		"type":            "oom",
+		"containerID":     container.ID,
+		"containerName":   "web",
+		"containerImage":  container.Image,
+		"containerLabels": []common.MapStr{{"key": "team", "value": "core"}},
+		"dockerSocket":    &socket,
+		"oom": common.MapStr{
+			"source":       OomSourceFailcnt,
+			"usage":        uint64(2048),
+			"maxUsage":     uint64(2048),
+			"limit":        uint64(2048),
+			"usage_p":      float64(1),
+			"failcnt":      uint64(3),
+			"failcntDelta": uint64(3),
+		},
+	}}, second)
+}
+
+/*
+TestEventGeneratorGetOomEventsOomKilled simulates a container killed by the OOM killer: the usage reported is the one
+of the previous sample, the usage of the killed container being meaningless.
+*/
+func TestEventGeneratorGetOomEventsOomKilled(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := getOomContainer()
+	timestamp := time.Now()
+	info := &docker.Container{State: docker.State{OOMKilled: true}}
+	var eventGenerator = EventGenerator{Socket: &socket}
+	eventGenerator.GetOomEvents(container, getOomStats(timestamp, 1536, 0), &docker.Container{})
+
+	// WHEN
+	first := eventGenerator.GetOomEvents(container, getOomStats(timestamp.Add(time.Second), 0, 0), info)
+	second := eventGenerator.GetOomEvents(container, getOomStats(timestamp.Add(2*time.Second), 0, 0), info)
+
+	// THEN
+	// the state is only reported once
+	assert.Len(t, first, 1)
+	assert.Empty(t, second)
+	oom := first[0]["oom"].(common.MapStr)
+	assert.Equal(t, OomSourceInspect, oom["source"])
+	assert.Equal(t, uint64(1536), oom["usage"])
+	assert.Equal(t, uint64(2048), oom["limit"])
+	assert.Equal(t, 0.75, oom["usage_p"])
+}
+
+/*
+TestEventGeneratorGetExitedOomEvent simulates a container killed by the OOM killer between two ticks, which is not
+running anymore: it is only found by listing the exited containers.
+*/
+func TestEventGeneratorGetExitedOomEvent(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := getOomContainer()
+	timestamp := time.Now()
+	info := &docker.Container{State: docker.State{OOMKilled: true, ExitCode: 137, FinishedAt: timestamp.Add(time.Second)}}
+	var eventGenerator = EventGenerator{Socket: &socket}
+	eventGenerator.GetOomEvents(container, getOomStats(timestamp, 1536, 0), &docker.Container{State: docker.State{Running: true}})
+
+	// WHEN
+	first := eventGenerator.GetExitedOomEvent(&docker.APIContainers{ID: container.ID}, info)
+	second := eventGenerator.GetExitedOomEvent(&docker.APIContainers{ID: container.ID}, info)
+	unknown := eventGenerator.GetExitedOomEvent(&docker.APIContainers{ID: "other_id"}, info)
+
+	// THEN
+	// the kill is reported once, with the last sample of the container
+	assert.Nil(t, second)
+	assert.Nil(t, unknown)
+	assert.Equal(t, common.Time(timestamp.Add(time.Second)), first["@timestamp"])
+	assert.Equal(t, "web", first["containerName"])
+	oom := first["oom"].(common.MapStr)
+	assert.Equal(t, OomSourceInspect, oom["source"])
+	assert.Equal(t, uint64(1536), oom["usage"])
+}
+
+/*
+TestEventGeneratorGetOomEventsRestarted simulates a container killed by the OOM killer and already restarted by its
+restart policy: the kill is reported by the daemon oom event, neither the restarting nor the restarted inspected
+states report it again.
+*/
+func TestEventGeneratorGetOomEventsRestarted(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := getOomContainer()
+	timestamp := time.Now()
+	var eventGenerator = EventGenerator{Socket: &socket}
+	eventGenerator.GetOomEvents(container, getOomStats(timestamp, 1536, 0), &docker.Container{State: docker.State{Running: true}})
+
+	// WHEN
+	daemon := eventGenerator.GetDaemonOomEvent(&docker.APIEvents{
+		Action: "oom", Type: "container", Actor: docker.APIActor{ID: container.ID}, TimeNano: timestamp.Add(time.Second).UnixNano(),
+	})
+	restarting := eventGenerator.GetOomEvents(container, getOomStats(timestamp.Add(2*time.Second), 0, 0), &docker.Container{
+		RestartCount: 1,
+		State:        docker.State{Running: true, Restarting: true, OOMKilled: true, FinishedAt: timestamp.Add(time.Second)},
+	})
+	restarted := eventGenerator.GetOomEvents(container, getOomStats(timestamp.Add(3*time.Second), 512, 0), &docker.Container{
+		RestartCount: 1,
+		State:        docker.State{Running: true, StartedAt: timestamp.Add(2 * time.Second), FinishedAt: timestamp.Add(time.Second)},
+	})
+
+	// THEN
+	assert.Equal(t, OomSourceDaemon, daemon["oom"].(common.MapStr)["source"])
+	assert.Empty(t, restarting)
+	assert.Empty(t, restarted)
+}
+
+func TestEventGeneratorGetDaemonOomEvent(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := getOomContainer()
+	timestamp := time.Unix(1464775200, 0)
+	var eventGenerator = EventGenerator{Socket: &socket}
+	eventGenerator.GetOomEvents(container, getOomStats(timestamp, 1024, 0), nil)
+
+	// WHEN
+	oomEvent := eventGenerator.GetDaemonOomEvent(&docker.APIEvents{
+		Action: "oom", Type: "container", Actor: docker.APIActor{ID: container.ID}, Time: timestamp.Unix() + 1,
+	})
+	startEvent := eventGenerator.GetDaemonOomEvent(&docker.APIEvents{
+		Action: "start", Type: "container", Actor: docker.APIActor{ID: container.ID},
+	})
+
+	// THEN
+	assert.Nil(t, startEvent)
+	assert.Equal(t, common.Time(timestamp.Add(time.Second)), oomEvent["@timestamp"])
+	assert.Equal(t, "web", oomEvent["containerName"])
+	oom := oomEvent["oom"].(common.MapStr)
+	assert.Equal(t, OomSourceDaemon, oom["source"])
+	assert.Equal(t, uint64(1024), oom["usage"])
+	assert.Equal(t, uint64(2048), oom["limit"])
+}
+
+func TestEventGeneratorGetDaemonOomEventUnknownContainer(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	var eventGenerator = EventGenerator{Socket: &socket}
+
+	// WHEN
+	// events of old API versions only have a status and an id
+	oomEvent := eventGenerator.GetDaemonOomEvent(&docker.APIEvents{Status: "oom", ID: "other_id", TimeNano: 1464775200000000000})
+
+	// THEN
+	assert.Equal(t, "other_id", oomEvent["containerID"])
+	assert.Equal(t, common.Time(time.Unix(1464775200, 0)), oomEvent["@timestamp"])
+	assert.Equal(t, uint64(0), oomEvent["oom"].(common.MapStr)["limit"])
+	assert.Nil(t, oomEvent["oom"].(common.MapStr)["usage_p"])
+}
+
+func TestEventGeneratorGetOomEventsCleaned(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	var eventGenerator = EventGenerator{
+		Socket:       &socket,
+		NetworkStats: EGNetworkStats{M: map[string]map[string]calculator.NetworkData{}},
+	}
+	eventGenerator.GetOomEvents(getOomContainer(), getOomStats(time.Now(), 1024, 0), nil)
+
+	// WHEN
+	eventGenerator.CleanOldStats(map[string]bool{})
+
+	// THEN
+	assert.Empty(t, eventGenerator.MemorySamples.M)
+}
+
+func getOomContainer() *docker.APIContainers {
+	return &docker.APIContainers{
+		ID:     "container_id",
+		Names:  []string{"/web"},
+		Labels: map[string]string{"team": "core"},
+	}
+}
+
+func getOomStats(read time.Time, usage uint64, failcnt uint64) *docker.Stats {
+	stats := &docker.Stats{Read: read}
+	stats.MemoryStats.Usage = usage
+	stats.MemoryStats.MaxUsage = usage
+	stats.MemoryStats.Limit = 2048
+	stats.MemoryStats.Failcnt = failcnt
+	return stats
+}
@@ -0,0 +1,227 @@
+package event
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+const defaultRollupPercentile = 95
+
+// unrolledTypes are the types of the events which are published as they come, as they are not samples
+var unrolledTypes = map[string]bool{"log": true, "oom": true, "alert": true, "crashloop": true}
+
+// EGRollup holds the samples of the current window for each event source when the rollup mode is enabled.
+// Windows are aligned on the wall clock: with a 1 minute window, they start at every minute.
+type EGRollup struct {
+	sync.Mutex
+	// Window is the rollup window length; the rollup mode is disabled when it is 0
+	Window time.Duration
+	// Percentile is published along min, max, mean and last values, between 0 and 100
+	Percentile float64
+	M          map[string]*rollupWindow
+}
+
+type rollupWindow struct {
+	start   time.Time
+	samples int
+	// last is the last event received during the window, used for non numeric fields
+	last common.MapStr
+	// values contains every sample of numeric fields, indexed by "section.field"
+	values map[string][]float64
+	// flags contains boolean fields, true if they were true in at least one sample
+	flags map[string]bool
+}
+
+/*
+RollupEvents holds the given events until the end of their window and returns the rollup events of all windows
+that ended before now. When the rollup mode is disabled, events are returned unchanged.
+
+A rollup event is the last event of the window, timestamped at the window start, with a rollup section
+containing the min, max, mean, last and percentile values of every numeric field.
+*/
+func (d *EventGenerator) RollupEvents(now time.Time, events []common.MapStr) []common.MapStr {
+	if d.Rollup.Window <= 0 {
+		return events
+	}
+
+	output := []common.MapStr{}
+	d.Rollup.Lock()
+	defer d.Rollup.Unlock()
+	if d.Rollup.M == nil {
+		d.Rollup.M = map[string]*rollupWindow{}
+	}
+
+	for _, event := range events {
+		timestamp, ok := event["@timestamp"].(common.Time)
+		// log and alert events are never held
+		eventType, _ := event["type"].(string)
+		if !ok || unrolledTypes[eventType] {
+			output = append(output, event)
+			continue
+		}
+
+		key := rollupKey(event)
+		start := time.Time(timestamp).Truncate(d.Rollup.Window)
+		window, exists := d.Rollup.M[key]
+		if exists && !window.start.Equal(start) {
+			output = append(output, d.buildRollupEvent(window))
+			exists = false
+		}
+		if !exists {
+			window = &rollupWindow{start: start, values: map[string][]float64{}, flags: map[string]bool{}}
+			d.Rollup.M[key] = window
+		}
+		window.add(event)
+	}
+
+	// flush ended windows, including the ones of containers which are gone
+	keys := []string{}
+	for key, window := range d.Rollup.M {
+		if !window.start.Add(d.Rollup.Window).After(now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		output = append(output, d.buildRollupEvent(d.Rollup.M[key]))
+		delete(d.Rollup.M, key)
+	}
+
+	logp.Debug("generator", "Rollup of %v events gives %v events", len(events), len(output))
+	return output
+}
+
+// rollupKey identifies the source of an event: one window is held per container, event type and network
+// (or aggregate group).
+func rollupKey(event common.MapStr) string {
+	parts := []string{}
+	if eventType, ok := event["type"].(string); ok {
+		parts = append(parts, eventType)
+	}
+	if containerID, ok := event["containerID"].(string); ok {
+		parts = append(parts, containerID)
+	}
+	if net, ok := event["net"].(common.MapStr); ok {
+		if name, ok := net["name"].(string); ok {
+			parts = append(parts, name)
+		}
+	}
+	if aggregate, ok := event["aggregate"].(common.MapStr); ok {
+		for _, field := range []string{"scope", "key", "value"} {
+			if value, ok := aggregate[field].(string); ok {
+				parts = append(parts, value)
+			}
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func (w *rollupWindow) add(event common.MapStr) {
+	w.samples++
+	w.last = event
+	for name, value := range event {
+		if section, ok := value.(common.MapStr); ok {
+			w.addSection(name, section)
+		}
+	}
+}
+
+func (w *rollupWindow) addSection(prefix string, section common.MapStr) {
+	for name, value := range section {
+		path := prefix + "." + name
+		if nested, ok := value.(common.MapStr); ok {
+			w.addSection(path, nested)
+		} else if flag, ok := value.(bool); ok {
+			w.flags[path] = w.flags[path] || flag
+		} else if number, ok := toFloat(value); ok {
+			w.values[path] = append(w.values[path], number)
+		}
+	}
+}
+
+func (d *EventGenerator) buildRollupEvent(window *rollupWindow) common.MapStr {
+	// the last event may still be read elsewhere (e.g. aggregation), so it is copied before being modified
+	event := cloneMapStr(window.last)
+	event["@timestamp"] = common.Time(window.start)
+
+	for path, flag := range window.flags {
+		setPath(event, path, flag)
+	}
+
+	percentile := d.Rollup.Percentile
+	if percentile <= 0 || percentile > 100 {
+		percentile = defaultRollupPercentile
+	}
+
+	stats := common.MapStr{}
+	for path, values := range window.values {
+		setPath(stats, path, summarize(values, percentile))
+	}
+
+	event["rollup"] = common.MapStr{
+		"start":      common.Time(window.start),
+		"end":        common.Time(window.start.Add(d.Rollup.Window)),
+		"samples":    window.samples,
+		"percentile": percentile,
+		"stats":      stats,
+	}
+	return event
+}
+
+// summarize computes min, max, mean, last and the nearest-rank percentile of the given samples
+func summarize(values []float64, percentile float64) common.MapStr {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	sum := float64(0)
+	for _, value := range values {
+		sum += value
+	}
+
+	rank := int(math.Ceil(percentile/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return common.MapStr{
+		"min":        sorted[0],
+		"max":        sorted[len(sorted)-1],
+		"mean":       sum / float64(len(values)),
+		"last":       values[len(values)-1],
+		"percentile": sorted[rank],
+	}
+}
+
+// setPath sets a value in nested MapStr, creating missing levels, e.g. "cpu.totalUsage"
+func setPath(m common.MapStr, path string, value interface{}) {
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		nested, ok := m[name].(common.MapStr)
+		if !ok {
+			nested = common.MapStr{}
+			m[name] = nested
+		}
+		m = nested
+	}
+	m[names[len(names)-1]] = value
+}
+
+// cloneMapStr copies nested MapStr, other values are shared
+func cloneMapStr(m common.MapStr) common.MapStr {
+	output := common.MapStr{}
+	for key, value := range m {
+		if nested, ok := value.(common.MapStr); ok {
+			output[key] = cloneMapStr(nested)
+		} else {
+			output[key] = value
+		}
+	}
+	return output
+}
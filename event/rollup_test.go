@@ -0,0 +1,119 @@
+package event
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEventGeneratorRollupEventsDisabled(t *testing.T) {
+	// GIVEN
+	var eventGenerator = EventGenerator{}
+	events := []common.MapStr{getRollupCpuEvent(time.Now(), 0.5, false)}
+
+	// WHEN
+	output := eventGenerator.RollupEvents(time.Now(), events)
+
+	// THEN
+	// events are published as is
+	assert.Equal(t, events, output)
+}
+
+/*
+TestEventGeneratorRollupEvents simulates four cpu samples of the same container, every 20 seconds with a one minute
+window: the first three samples are in the first window, the fourth one in the second window.
+*/
+func TestEventGeneratorRollupEvents(t *testing.T) {
+	// GIVEN
+	start := time.Date(2016, 6, 1, 10, 0, 0, 0, time.UTC)
+	var eventGenerator = EventGenerator{Rollup: EGRollup{Window: time.Minute, Percentile: 50}}
+
+	// WHEN
+	first := eventGenerator.RollupEvents(start.Add(5*time.Second), []common.MapStr{getRollupCpuEvent(start.Add(5*time.Second), 0.5, false)})
+	second := eventGenerator.RollupEvents(start.Add(25*time.Second), []common.MapStr{getRollupCpuEvent(start.Add(25*time.Second), 0.1, true)})
+	last := getRollupCpuEvent(start.Add(45*time.Second), 0.3, false)
+	third := eventGenerator.RollupEvents(start.Add(45*time.Second), []common.MapStr{last})
+	fourth := eventGenerator.RollupEvents(start.Add(65*time.Second), []common.MapStr{getRollupCpuEvent(start.Add(65*time.Second), 0.9, false)})
+
+	// THEN
+	// nothing is published until the end of the window
+	assert.Empty(t, first)
+	assert.Empty(t, second)
+	assert.Empty(t, third)
+	assert.Equal(t, 1, len(fourth))
+
+	event := fourth[0]
+	assert.Equal(t, common.Time(start), event["@timestamp"])
+	assert.Equal(t, "cpu", event["type"])
+	assert.Equal(t, "container_id", event["containerID"])
+	// non numeric fields come from the last sample, booleans are true if they were true in any sample
+	assert.Equal(t, 0.3, event["cpu"].(common.MapStr)["totalUsage"])
+	assert.Equal(t, true, event["cpu"].(common.MapStr)["counterReset"])
+
+	rollup := event["rollup"].(common.MapStr)
+	assert.Equal(t, common.Time(start), rollup["start"])
+	assert.Equal(t, common.Time(start.Add(time.Minute)), rollup["end"])
+	assert.Equal(t, 3, rollup["samples"])
+	assert.Equal(t, float64(50), rollup["percentile"])
+	totalUsage := rollup["stats"].(common.MapStr)["cpu"].(common.MapStr)["totalUsage"].(common.MapStr)
+	assert.Equal(t, 0.1, totalUsage["min"])
+	assert.Equal(t, 0.5, totalUsage["max"])
+	assert.InDelta(t, 0.3, totalUsage["mean"], 0.0001)
+	assert.Equal(t, 0.3, totalUsage["last"])
+	assert.Equal(t, 0.3, totalUsage["percentile"])
+
+	// the held event is not modified
+	assert.Equal(t, false, last["cpu"].(common.MapStr)["counterReset"])
+	assert.Nil(t, last["rollup"])
+}
+
+func TestEventGeneratorRollupEventsFlushesGoneContainers(t *testing.T) {
+	// GIVEN
+	start := time.Date(2016, 6, 1, 10, 0, 0, 0, time.UTC)
+	var eventGenerator = EventGenerator{Rollup: EGRollup{Window: time.Minute}}
+	eventGenerator.RollupEvents(start, []common.MapStr{getRollupCpuEvent(start, 0.5, false)})
+
+	// WHEN
+	// no more events for the container, but the window ended
+	output := eventGenerator.RollupEvents(start.Add(time.Minute), []common.MapStr{})
+
+	// THEN
+	assert.Equal(t, 1, len(output))
+	assert.Equal(t, float64(defaultRollupPercentile), output[0]["rollup"].(common.MapStr)["percentile"])
+	assert.Empty(t, eventGenerator.Rollup.M)
+}
+
+func TestEventGeneratorRollupEventsLog(t *testing.T) {
+	// GIVEN
+	var eventGenerator = EventGenerator{Rollup: EGRollup{Window: time.Minute}}
+	events := []common.MapStr{
+		eventGenerator.GetLogEvent("info", "message"),
+		{"@timestamp": common.Time(time.Now()), "type": "oom", "containerID": "container_id"},
+	}
+
+	// WHEN
+	output := eventGenerator.RollupEvents(time.Now(), events)
+
+	// THEN
+	// log and oom events are not held
+	assert.Equal(t, events, output)
+}
+
+func TestRollupKey(t *testing.T) {
+	assert.Equal(t, "cpu/container_id", rollupKey(common.MapStr{"type": "cpu", "containerID": "container_id"}))
+	assert.Equal(t, "net/container_id/eth0", rollupKey(common.MapStr{"type": "net", "containerID": "container_id", "net": common.MapStr{"name": "eth0"}}))
+	assert.Equal(t, "aggregate/label/team/core", rollupKey(common.MapStr{"type": "aggregate", "aggregate": common.MapStr{"scope": "label", "key": "team", "value": "core"}}))
+}
+
+func getRollupCpuEvent(timestamp time.Time, totalUsage float64, counterReset bool) common.MapStr {
+	return common.MapStr{
+		"@timestamp":  common.Time(timestamp),
+		"type":        "cpu",
+		"containerID": "container_id",
+		"cpu": common.MapStr{
+			"totalUsage":   totalUsage,
+			"counterReset": counterReset,
+		},
+	}
+}
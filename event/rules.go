@@ -0,0 +1,278 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Alert statuses
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+var ruleOperators = map[string]func(float64, float64) bool{
+	">":  func(value, threshold float64) bool { return value > threshold },
+	">=": func(value, threshold float64) bool { return value >= threshold },
+	"<":  func(value, threshold float64) bool { return value < threshold },
+	"<=": func(value, threshold float64) bool { return value <= threshold },
+	"==": func(value, threshold float64) bool { return value == threshold },
+	"!=": func(value, threshold float64) bool { return value != threshold },
+}
+
+/*
+Rule is a threshold alerting rule, parsed from an expression such as:
+
+	memory.usage_p > 0.9 for 3 ticks on label team=core
+
+The field is the path of a numeric field of the events, the "for" clause is the number of consecutive ticks the
+condition must hold to fire (1 by default), and the optional "on" clause restricts the rule to the containers
+having a label (on label <key>=<value>) or a name (on name <name>).
+
+Resolve is the threshold under which (or above which, for < and <= operators) a firing rule is resolved, it is
+the rule threshold by default. A resolve threshold with some margin avoids flapping alerts when the value stays
+around the threshold.
+*/
+type Rule struct {
+	Name       string
+	Expression string
+	Field      string
+	Operator   string
+	Threshold  float64
+	Resolve    float64
+	Ticks      int
+	LabelKey   string
+	LabelValue string
+	Container  string
+}
+
+// EGRules holds the alerting rules and the state of each rule for each event source
+type EGRules struct {
+	sync.Mutex
+	Rules []Rule
+	M     map[string]*ruleState
+}
+
+type ruleState struct {
+	firing bool
+	// ticks is the number of consecutive ticks the firing (or resolving when firing) condition held
+	ticks int
+	since time.Time
+	// containerID is empty for aggregate events
+	containerID string
+	// rule, event and value are the last evaluation, to resolve the alert when the container is gone
+	rule  Rule
+	event common.MapStr
+	value float64
+}
+
+/*
+ParseRule parses a rule expression. The resolve threshold is the rule threshold when nil.
+*/
+func ParseRule(name string, expression string, resolve *float64) (Rule, error) {
+	rule := Rule{Name: name, Expression: expression, Ticks: 1}
+	fields := strings.Fields(expression)
+	if len(fields) < 3 {
+		return rule, errors.New("expected <field> <operator> <threshold> in rule " + expression)
+	}
+
+	rule.Field = fields[0]
+	rule.Operator = fields[1]
+	if _, ok := ruleOperators[rule.Operator]; !ok {
+		return rule, fmt.Errorf("unknown operator %v in rule %v", rule.Operator, expression)
+	}
+	var err error
+	if rule.Threshold, err = strconv.ParseFloat(fields[2], 64); err != nil {
+		return rule, fmt.Errorf("invalid threshold %v in rule %v", fields[2], expression)
+	}
+	rule.Resolve = rule.Threshold
+	if resolve != nil {
+		rule.Resolve = *resolve
+	}
+
+	clauses := fields[3:]
+	if len(clauses) >= 3 && clauses[0] == "for" && (clauses[2] == "ticks" || clauses[2] == "tick") {
+		if rule.Ticks, err = strconv.Atoi(clauses[1]); err != nil || rule.Ticks < 1 {
+			return rule, fmt.Errorf("invalid ticks count %v in rule %v", clauses[1], expression)
+		}
+		clauses = clauses[3:]
+	}
+	if len(clauses) == 3 && clauses[0] == "on" && clauses[1] == "label" {
+		keyValue := strings.SplitN(clauses[2], "=", 2)
+		if len(keyValue) != 2 {
+			return rule, fmt.Errorf("expected on label <key>=<value> in rule %v", expression)
+		}
+		// labels keys are published with dots replaced by underscores
+		rule.LabelKey, rule.LabelValue = strings.Replace(keyValue[0], ".", "_", -1), keyValue[1]
+		clauses = clauses[3:]
+	} else if len(clauses) == 3 && clauses[0] == "on" && clauses[1] == "name" {
+		rule.Container = clauses[2]
+		clauses = clauses[3:]
+	}
+	if len(clauses) > 0 {
+		return rule, fmt.Errorf("unexpected %v in rule %v", strings.Join(clauses, " "), expression)
+	}
+	if rule.Name == "" {
+		rule.Name = expression
+	}
+	return rule, nil
+}
+
+/*
+EvaluateRules evaluates the rules against the given events, and returns an alert event each time a rule starts
+firing or is resolved for an event source. An event source (a container, a network of a container, an aggregate)
+only generates one alert while the rule fires.
+*/
+func (d *EventGenerator) EvaluateRules(events []common.MapStr) []common.MapStr {
+	alerts := []common.MapStr{}
+	if len(d.Rules.Rules) == 0 {
+		return alerts
+	}
+
+	d.Rules.Lock()
+	defer d.Rules.Unlock()
+	if d.Rules.M == nil {
+		d.Rules.M = map[string]*ruleState{}
+	}
+
+	for _, event := range events {
+		for _, rule := range d.Rules.Rules {
+			value, ok := toFloat(getPath(event, rule.Field))
+			if !ok || !rule.matches(event) {
+				continue
+			}
+
+			key := rule.Name + "/" + rollupKey(event)
+			state, exists := d.Rules.M[key]
+			if !exists {
+				containerID, _ := event["containerID"].(string)
+				state = &ruleState{containerID: containerID}
+				d.Rules.M[key] = state
+			}
+
+			state.rule, state.event, state.value = rule, event, value
+			compare := ruleOperators[rule.Operator]
+			if !state.firing && compare(value, rule.Threshold) || state.firing && !compare(value, rule.Resolve) {
+				state.ticks++
+			} else {
+				state.ticks = 0
+			}
+			if state.ticks < rule.Ticks {
+				continue
+			}
+
+			state.firing = !state.firing
+			state.ticks = 0
+			timestamp := time.Now()
+			if eventTime, ok := event["@timestamp"].(common.Time); ok {
+				timestamp = time.Time(eventTime)
+			}
+			if state.firing {
+				state.since = timestamp
+			}
+			logp.Debug("generator", "Rule %v %v for %v", rule.Name, alertStatus(state.firing), key)
+			alerts = append(alerts, d.buildAlertEvent(timestamp, event, rule, value, state))
+		}
+	}
+	return alerts
+}
+
+func (r *Rule) matches(event common.MapStr) bool {
+	if r.Container != "" && event["containerName"] != r.Container {
+		return false
+	}
+	if r.LabelKey == "" {
+		return true
+	}
+	labels, _ := event["containerLabels"].([]common.MapStr)
+	for _, label := range labels {
+		if label["key"] == r.LabelKey && label["value"] == r.LabelValue {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *EventGenerator) buildAlertEvent(timestamp time.Time, event common.MapStr, rule Rule, value float64, state *ruleState) common.MapStr {
+	alert := common.MapStr{
+		"rule":       rule.Name,
+		"expression": rule.Expression,
+		"status":     alertStatus(state.firing),
+		"field":      rule.Field,
+		"value":      value,
+		"threshold":  rule.Threshold,
+		"resolve":    rule.Resolve,
+		"since":      common.Time(state.since),
+		"source":     event["type"],
+	}
+	// aggregate events are identified by their scope, key and value
+	if aggregate, ok := event["aggregate"].(common.MapStr); ok {
+		group := common.MapStr{}
+		for _, field := range []string{"scope", "key", "value"} {
+			if value, ok := aggregate[field]; ok {
+				group[field] = value
+			}
+		}
+		alert["aggregate"] = group
+	}
+
+	output := common.MapStr{
+		"@timestamp":   common.Time(timestamp),
+		"type":         "alert",
+		"dockerSocket": d.Socket,
+		"alert":        alert,
+	}
+	for _, field := range []string{"containerID", "containerName", "containerImage", "containerLabels"} {
+		if value, ok := event[field]; ok {
+			output[field] = value
+		}
+	}
+	return output
+}
+
+func alertStatus(firing bool) string {
+	if firing {
+		return AlertFiring
+	}
+	return AlertResolved
+}
+
+// getPath returns the value of a dotted path of nested MapStr, or nil
+func getPath(m common.MapStr, path string) interface{} {
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		nested, ok := m[name].(common.MapStr)
+		if !ok {
+			return nil
+		}
+		m = nested
+	}
+	return m[names[len(names)-1]]
+}
+
+// clean removes the rule states of the containers which are gone, and returns the states of their firing rules,
+// which are resolved
+func (r *EGRules) clean(live map[string]bool) []*ruleState {
+	r.Lock()
+	defer r.Unlock()
+	resolved := []*ruleState{}
+	for key, state := range r.M {
+		if state.containerID == "" || live[state.containerID] {
+			continue
+		}
+		if state.firing {
+			logp.Debug("generator", "Rule %v resolved for %v, the container is gone", state.rule.Name, key)
+			state.firing = false
+			resolved = append(resolved, state)
+		}
+		delete(r.M, key)
+	}
+	return resolved
+}
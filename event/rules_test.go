@@ -0,0 +1,184 @@
+package event
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/ingensi/dockbeat/calculator"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	// WHEN
+	rule, err := ParseRule("core_memory", "memory.usage_p > 0.9 for 3 ticks on label team.name=core", nil)
+
+	// THEN
+	assert.Nil(t, err)
+	assert.Equal(t, Rule{
+		Name:       "core_memory",
+		Expression: "memory.usage_p > 0.9 for 3 ticks on label team.name=core",
+		Field:      "memory.usage_p",
+		Operator:   ">",
+		Threshold:  0.9,
+		Resolve:    0.9,
+		Ticks:      3,
+		LabelKey:   "team_name",
+		LabelValue: "core",
+	}, rule)
+}
+
+func TestParseRuleDefaults(t *testing.T) {
+	// GIVEN
+	resolve := 10.0
+
+	// WHEN
+	rule, err := ParseRule("", "pids.current <= 5 on name web", &resolve)
+
+	// THEN
+	assert.Nil(t, err)
+	assert.Equal(t, "pids.current <= 5 on name web", rule.Name)
+	assert.Equal(t, 1, rule.Ticks)
+	assert.Equal(t, 10.0, rule.Resolve)
+	assert.Equal(t, "web", rule.Container)
+}
+
+func TestParseRuleInvalid(t *testing.T) {
+	for _, expression := range []string{
+		"memory.usage_p > 0.9 for",
+		"memory.usage_p ~ 0.9",
+		"memory.usage_p > high",
+		"memory.usage_p > 0.9 for 0 ticks",
+		"memory.usage_p > 0.9 on label team",
+		"memory.usage_p > 0.9 on image nginx",
+	} {
+		_, err := ParseRule("", expression, nil)
+		assert.NotNil(t, err, expression)
+	}
+}
+
+/*
+TestEventGeneratorEvaluateRules simulates a memory usage going above the threshold, staying around it, then going
+under the resolve threshold.
+*/
+func TestEventGeneratorEvaluateRules(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	resolve := 0.8
+	rule, _ := ParseRule("memory", "memory.usage_p > 0.9 for 2 ticks on label team=core", &resolve)
+	var eventGenerator = EventGenerator{Socket: &socket, Rules: EGRules{Rules: []Rule{rule}}}
+	timestamp := time.Now()
+
+	// WHEN
+	alerts := [][]common.MapStr{}
+	for i, usage := range []float64{0.95, 0.5, 0.95, 0.95, 0.85, 0.95, 0.7, 0.7} {
+		event := getRuleEvent("container_id", "core", timestamp.Add(time.Duration(i)*time.Second), usage)
+		alerts = append(alerts, eventGenerator.EvaluateRules([]common.MapStr{event}))
+	}
+
+	// THEN
+	// the rule fires after 2 consecutive ticks above 0.9, is not resolved while the usage stays above 0.8 and is
+	// resolved after 2 consecutive ticks under 0.8
+	for i, expected := range []int{0, 0, 0, 1, 0, 0, 0, 1} {
+		assert.Len(t, alerts[i], expected, "tick %v", i)
+	}
+	firing := alerts[3][0]
+	assert.Equal(t, "alert", firing["type"])
+	assert.Equal(t, "container_id", firing["containerID"])
+	assert.Equal(t, common.MapStr{
+		"rule":       "memory",
+		"expression": "memory.usage_p > 0.9 for 2 ticks on label team=core",
+		"status":     AlertFiring,
+		"field":      "memory.usage_p",
+		"value":      0.95,
+		"threshold":  0.9,
+		"resolve":    0.8,
+		"since":      common.Time(timestamp.Add(3 * time.Second)),
+		"source":     "memory",
+	}, firing["alert"])
+	resolved := alerts[7][0]["alert"].(common.MapStr)
+	assert.Equal(t, AlertResolved, resolved["status"])
+	assert.Equal(t, common.Time(timestamp.Add(3*time.Second)), resolved["since"])
+	assert.Equal(t, common.Time(timestamp.Add(7*time.Second)), alerts[7][0]["@timestamp"])
+}
+
+func TestEventGeneratorEvaluateRulesFilters(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	rule, _ := ParseRule("memory", "memory.usage_p > 0.9 on label team=core", nil)
+	var eventGenerator = EventGenerator{Socket: &socket, Rules: EGRules{Rules: []Rule{rule}}}
+	events := []common.MapStr{
+		getRuleEvent("core_id", "core", time.Now(), 0.95),
+		getRuleEvent("other_id", "other", time.Now(), 0.95),
+		{"type": "cpu", "containerID": "core_id", "cpu": common.MapStr{"totalUsage": 0.95}},
+	}
+
+	// WHEN
+	alerts := eventGenerator.EvaluateRules(events)
+
+	// THEN
+	// each container is evaluated separately, only containers with the label and events with the field match
+	assert.Len(t, alerts, 1)
+	assert.Equal(t, "core_id", alerts[0]["containerID"])
+}
+
+func TestEventGeneratorEvaluateRulesCleaned(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	rule, _ := ParseRule("memory", "memory.usage_p > 0.9", nil)
+	var eventGenerator = EventGenerator{
+		Socket:       &socket,
+		NetworkStats: EGNetworkStats{M: map[string]map[string]calculator.NetworkData{}},
+		Rules:        EGRules{Rules: []Rule{rule}},
+	}
+	eventGenerator.EvaluateRules([]common.MapStr{
+		getRuleEvent("firing_id", "core", time.Now(), 0.95),
+		getRuleEvent("normal_id", "core", time.Now(), 0.5),
+		getRuleEvent("running_id", "core", time.Now(), 0.95),
+	})
+
+	// WHEN
+	alerts := eventGenerator.CleanOldStats(ContainerIDs([]docker.APIContainers{{ID: "running_id"}}))
+
+	// THEN
+	// the firing rules of the containers which are gone are resolved
+	assert.Len(t, eventGenerator.Rules.M, 1)
+	assert.Len(t, alerts, 1)
+	assert.Equal(t, "firing_id", alerts[0]["containerID"])
+	resolved := alerts[0]["alert"].(common.MapStr)
+	assert.Equal(t, AlertResolved, resolved["status"])
+	assert.Equal(t, 0.95, resolved["value"])
+}
+
+func TestEventGeneratorEvaluateRulesAggregatesNotCleaned(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	rule, _ := ParseRule("containers", "aggregate.containers > 10", nil)
+	var eventGenerator = EventGenerator{
+		Socket:       &socket,
+		NetworkStats: EGNetworkStats{M: map[string]map[string]calculator.NetworkData{}},
+		Rules:        EGRules{Rules: []Rule{rule}},
+	}
+	eventGenerator.EvaluateRules([]common.MapStr{
+		{"type": "aggregate", "aggregate": common.MapStr{"scope": "host", "containers": 12}},
+	})
+
+	// WHEN
+	alerts := eventGenerator.CleanOldStats(map[string]bool{})
+
+	// THEN
+	// aggregate rules don't belong to a container
+	assert.Empty(t, alerts)
+	assert.Len(t, eventGenerator.Rules.M, 1)
+}
+
+func getRuleEvent(containerID string, team string, timestamp time.Time, usage float64) common.MapStr {
+	return common.MapStr{
+		"@timestamp":      common.Time(timestamp),
+		"type":            "memory",
+		"containerID":     containerID,
+		"containerName":   "web",
+		"containerLabels": []common.MapStr{{"key": "team", "value": team}},
+		"memory":          common.MapStr{"usage_p": usage},
+	}
+}
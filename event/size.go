@@ -0,0 +1,62 @@
+package event
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// EGContainerSizes holds the last sizes of each container. Computing the sizes is expensive for the docker daemon,
+// they are collected on a slower schedule than the stats and added to the container events.
+type EGContainerSizes struct {
+	sync.RWMutex
+	M map[string]containerSize
+}
+
+type containerSize struct {
+	time       time.Time
+	sizeRw     int64
+	sizeRootFs int64
+	// rwRate is the growth of the writable layer in bytes per second since the previous collection
+	rwRate  float64
+	hasRate bool
+}
+
+/*
+UpdateContainerSizes saves the sizes of the given containers, listed with the Size option, and computes the growth
+rate of their writable layer. Containers which are not in the list are forgotten.
+*/
+func (d *EventGenerator) UpdateContainerSizes(now time.Time, containers []docker.APIContainers) {
+	d.ContainerSizes.Lock()
+	defer d.ContainerSizes.Unlock()
+
+	sizes := map[string]containerSize{}
+	for _, container := range containers {
+		size := containerSize{time: now, sizeRw: container.SizeRw, sizeRootFs: container.SizeRootFs}
+		if previous, ok := d.ContainerSizes.M[container.ID]; ok {
+			if elapsed := now.Sub(previous.time).Seconds(); elapsed > 0 {
+				size.rwRate = float64(container.SizeRw-previous.sizeRw) / elapsed
+				size.hasRate = true
+			}
+		}
+		sizes[container.ID] = size
+	}
+	d.ContainerSizes.M = sizes
+}
+
+// addContainerSize sets the last collected sizes of a container in the container section of an event
+func (d *EventGenerator) addContainerSize(id string, section common.MapStr) {
+	d.ContainerSizes.RLock()
+	size, ok := d.ContainerSizes.M[id]
+	d.ContainerSizes.RUnlock()
+	if !ok {
+		return
+	}
+	section["sizeRw"] = size.sizeRw
+	section["sizeRootFs"] = size.sizeRootFs
+	if size.hasRate {
+		section["sizeRwRate"] = size.rwRate
+	}
+}
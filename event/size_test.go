@@ -0,0 +1,58 @@
+package event
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEventGeneratorGetContainerEventWithoutSizes(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := &docker.APIContainers{ID: "container_id", Names: []string{"/web"}}
+	var eventGenerator = EventGenerator{Socket: &socket}
+
+	// WHEN
+	event := eventGenerator.GetContainerEvent(container, &docker.Stats{Read: time.Now()})
+
+	// THEN
+	// sizes are the listed ones until they are collected
+	assert.Equal(t, int64(0), event["container"].(common.MapStr)["sizeRw"])
+	assert.Nil(t, event["container"].(common.MapStr)["sizeRwRate"])
+}
+
+/*
+TestEventGeneratorUpdateContainerSizes simulates a container whose writable layer grows by 1MB per minute, and a
+container which is gone at the second collection.
+*/
+func TestEventGeneratorUpdateContainerSizes(t *testing.T) {
+	// GIVEN
+	socket := "unix:///some/docker/socket"
+	container := &docker.APIContainers{ID: "container_id", Names: []string{"/web"}}
+	timestamp := time.Now()
+	var eventGenerator = EventGenerator{Socket: &socket}
+
+	// WHEN
+	eventGenerator.UpdateContainerSizes(timestamp, []docker.APIContainers{
+		{ID: "container_id", SizeRw: 1024 * 1024, SizeRootFs: 200 * 1024 * 1024},
+		{ID: "gone_id", SizeRw: 1024},
+	})
+	first := eventGenerator.GetContainerEvent(container, &docker.Stats{Read: timestamp})
+	eventGenerator.UpdateContainerSizes(timestamp.Add(time.Minute), []docker.APIContainers{
+		{ID: "container_id", SizeRw: 2 * 1024 * 1024, SizeRootFs: 201 * 1024 * 1024},
+	})
+	second := eventGenerator.GetContainerEvent(container, &docker.Stats{Read: timestamp.Add(time.Minute)})
+
+	// THEN
+	// the rate is only known from the second collection
+	firstContainer := first["container"].(common.MapStr)
+	assert.Equal(t, int64(1024*1024), firstContainer["sizeRw"])
+	assert.Equal(t, int64(200*1024*1024), firstContainer["sizeRootFs"])
+	assert.Nil(t, firstContainer["sizeRwRate"])
+	secondContainer := second["container"].(common.MapStr)
+	assert.Equal(t, int64(2*1024*1024), secondContainer["sizeRw"])
+	assert.Equal(t, float64(1024*1024)/60, secondContainer["sizeRwRate"])
+	assert.Len(t, eventGenerator.ContainerSizes.M, 1)
+}
@@ -6,6 +6,13 @@ import (
 	"github.com/elastic/beats/libbeat/beat"
 
 	"github.com/ingensi/dockbeat/beater"
+
+	// dockbeat outputs, registered in the libbeat outputs
+	_ "github.com/ingensi/dockbeat/outputs/fluentd"
+	_ "github.com/ingensi/dockbeat/outputs/graphite"
+	_ "github.com/ingensi/dockbeat/outputs/influxdb"
+	_ "github.com/ingensi/dockbeat/outputs/syslog"
+	_ "github.com/ingensi/dockbeat/outputs/webhook"
 )
 
 func main() {
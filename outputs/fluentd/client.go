@@ -0,0 +1,138 @@
+package fluentd
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+
+	dockbeatoutputs "github.com/ingensi/dockbeat/outputs"
+)
+
+// client sends the events to an aggregator, one message per tag of a batch
+type client struct {
+	address   string
+	timeout   time.Duration
+	batchSize int
+	settings  settings
+	conn      net.Conn
+	reader    *bufio.Reader
+}
+
+func newClient(address string, timeout time.Duration, batchSize int, s settings) *client {
+	return &client{address: address, timeout: timeout, batchSize: batchSize, settings: s}
+}
+
+func (c *client) Connect(timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", c.address, timeout)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	return nil
+}
+
+func (c *client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *client) IsConnected() bool {
+	return c.conn != nil
+}
+
+func (c *client) PublishEvent(event common.MapStr) error {
+	_, err := c.PublishEvents([]common.MapStr{event})
+	return err
+}
+
+/*
+PublishEvents sends a batch of events, the remaining ones are returned to be published by the next call. When ack
+is enabled, the batch is published once all its messages are acknowledged: on error, the whole batch is sent again.
+*/
+func (c *client) PublishEvents(events []common.MapStr) ([]common.MapStr, error) {
+	batch := events
+	if len(batch) > c.batchSize {
+		batch = batch[:c.batchSize]
+	}
+
+	messages, chunks, err := c.encode(batch)
+	if err != nil {
+		return events, err
+	}
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	if _, err := c.conn.Write(messages); err != nil {
+		c.Close()
+		return events, err
+	}
+	for _, chunk := range chunks {
+		ack, err := readAck(c.reader)
+		if err == nil && ack != chunk {
+			err = fmt.Errorf("fluentd ack %v doesn't match the chunk %v", ack, chunk)
+		}
+		if err != nil {
+			c.Close()
+			return events, err
+		}
+	}
+	debug("Sent %v events", len(batch))
+	return events[len(batch):], nil
+}
+
+// encode returns the forward mode messages of a batch, one per tag, and their chunk ids when ack is enabled
+func (c *client) encode(events []common.MapStr) ([]byte, []string, error) {
+	tags := []string{}
+	entries := map[string][]common.MapStr{}
+	for _, event := range events {
+		eventTag := tag(c.settings.Tag, event)
+		if _, ok := entries[eventTag]; !ok {
+			tags = append(tags, eventTag)
+		}
+		entries[eventTag] = append(entries[eventTag], event)
+	}
+
+	messages := []byte{}
+	chunks := []string{}
+	for _, eventTag := range tags {
+		option := map[string]interface{}{"size": len(entries[eventTag])}
+		if c.settings.Ack {
+			chunk, err := newChunk()
+			if err != nil {
+				return nil, nil, err
+			}
+			option["chunk"] = chunk
+			chunks = append(chunks, chunk)
+		}
+
+		messages = appendArrayHeader(messages, 3)
+		messages = appendString(messages, eventTag)
+		messages = appendArrayHeader(messages, len(entries[eventTag]))
+		for _, event := range entries[eventTag] {
+			messages = appendArrayHeader(messages, 2)
+			messages = appendInt(messages, dockbeatoutputs.Timestamp(event).Unix())
+			messages = appendMap(messages, event)
+		}
+		messages = appendMap(messages, option)
+	}
+	return messages, chunks, nil
+}
+
+// newChunk returns a random chunk id, 128 bits encoded in base64 like the fluentd forward output
+func newChunk() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(id), nil
+}
@@ -0,0 +1,104 @@
+/*
+Package fluentd defines the fluentd output, which sends the events to Fluentd or Fluent Bit aggregators with the
+forward protocol: messages in the forward mode, [tag, [[time, record], ...], option], encoded with msgpack over TCP.
+
+The record is the event, the time is the event timestamp in seconds. The tag is built from a template,
+dockbeat.{type} by default, whose {type}, {host} and {container} placeholders are replaced by the event values.
+
+When ack is enabled, each message carries a chunk id which must be acknowledged by the aggregator before the batch
+is considered published, for an at-least-once delivery: batches without ack are sent again, to the next aggregator
+with the fail over connection mode.
+*/
+package fluentd
+
+import (
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/mode"
+
+	dockbeatoutputs "github.com/ingensi/dockbeat/outputs"
+)
+
+const (
+	defaultPort = 24224
+	defaultTag  = "dockbeat.{type}"
+)
+
+var debug = logp.MakeDebug("fluentd")
+
+func init() {
+	outputs.RegisterOutputPlugin("fluentd", fluentdOutputPlugin{})
+}
+
+// settings of the forward messages
+type settings struct {
+	// Tag is the template of the message tags
+	Tag string
+	// Ack requires the aggregators to acknowledge each message
+	Ack bool
+}
+
+type fluentdOutputPlugin struct{}
+
+func (p fluentdOutputPlugin) NewOutput(config *outputs.MothershipConfig, topologyExpire int) (outputs.Outputer, error) {
+	s := settings{}
+	if err := dockbeatoutputs.ReadSettings("fluentd", &s); err != nil {
+		return nil, err
+	}
+	return newFluentd(*config, s)
+}
+
+type fluentd struct {
+	mode mode.ConnectionMode
+}
+
+func newFluentd(config outputs.MothershipConfig, s settings) (*fluentd, error) {
+	if s.Tag == "" {
+		s.Tag = defaultTag
+	}
+	timeout := dockbeatoutputs.Timeout(config)
+	batchSize := dockbeatoutputs.BatchSize(config)
+
+	clients, err := mode.MakeClients(config, func(host string) (mode.ProtocolClient, error) {
+		return newClient(dockbeatoutputs.WithPort(host, config.Port, defaultPort), timeout, batchSize, s), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m, err := dockbeatoutputs.NewConnectionMode(config, clients, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &fluentd{mode: m}, nil
+}
+
+func (f *fluentd) PublishEvent(signaler outputs.Signaler, opts outputs.Options, event common.MapStr) error {
+	return f.mode.PublishEvent(signaler, opts, event)
+}
+
+// BulkPublish implements the BulkOutputer interface, events are sent in batches of bulk_max_size events
+func (f *fluentd) BulkPublish(signaler outputs.Signaler, opts outputs.Options, events []common.MapStr) error {
+	return f.mode.PublishEvents(signaler, opts, events)
+}
+
+// tag returns the tag of an event, empty components (e.g. the container of events without container) being removed
+func tag(template string, event common.MapStr) string {
+	eventType, _ := event["type"].(string)
+	container, _ := event["containerName"].(string)
+	replacer := strings.NewReplacer(
+		"{type}", eventType,
+		"{host}", dockbeatoutputs.Hostname(event),
+		"{container}", container,
+	)
+	components := strings.Split(replacer.Replace(template), ".")
+	output := components[:0]
+	for _, component := range components {
+		if component != "" {
+			output = append(output, component)
+		}
+	}
+	return strings.Join(output, ".")
+}
@@ -0,0 +1,143 @@
+package fluentd
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/ingensi/dockbeat/outputs/outputstest"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTag(t *testing.T) {
+	event := outputstest.PidsEvent(1)
+	assert.Equal(t, "dockbeat.pids", tag(defaultTag, event))
+	assert.Equal(t, "docker.docker1.web.pids", tag("docker.{host}.{container}.{type}", event))
+
+	// empty components are removed
+	delete(event, "containerName")
+	assert.Equal(t, "docker.docker1.pids", tag("docker.{host}.{container}.{type}", event))
+}
+
+func TestClientEncode(t *testing.T) {
+	// GIVEN
+	client := newClient("localhost:24224", time.Second, 10, settings{Tag: "{type}"})
+	log := common.MapStr{"@timestamp": common.Time(time.Unix(1464775200, 0)), "type": "log"}
+
+	// WHEN
+	messages, chunks, err := client.encode([]common.MapStr{log, outputstest.PidsEvent(1), log})
+
+	// THEN
+	// one message per tag
+	assert.Nil(t, err)
+	assert.Empty(t, chunks)
+	expected := appendValue(nil, []interface{}{"log", []interface{}{
+		[]interface{}{1464775200, log},
+		[]interface{}{1464775200, log},
+	}, map[string]interface{}{"size": 2}})
+	expected = appendValue(expected, []interface{}{"pids", []interface{}{
+		[]interface{}{1464775200, outputstest.PidsEvent(1)},
+	}, map[string]interface{}{"size": 1}})
+	assert.Equal(t, expected, messages)
+}
+
+func TestClientPublishEventsAck(t *testing.T) {
+	// GIVEN
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	acks := []bool{true, false}
+	go func() {
+		for _, ack := range acks {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			chunk, err := readChunk(conn)
+			if err == nil && ack {
+				conn.Write(appendValue(nil, map[string]interface{}{"ack": chunk}))
+			}
+			conn.Close()
+		}
+	}()
+	client := newClient(listener.Addr().String(), time.Second, 10, settings{Tag: defaultTag, Ack: true})
+	events := []common.MapStr{outputstest.PidsEvent(1), outputstest.PidsEvent(2)}
+
+	// WHEN
+	client.Connect(time.Second)
+	acked, ackedErr := client.PublishEvents(events)
+	client.Connect(time.Second)
+	lost, lostErr := client.PublishEvents(events)
+
+	// THEN
+	// batches without ack are returned to be sent again
+	assert.Nil(t, ackedErr)
+	assert.Empty(t, acked)
+	assert.NotNil(t, lostErr)
+	assert.Equal(t, events, lost)
+	assert.False(t, client.IsConnected())
+}
+
+func TestFluentdBulkPublishFailOver(t *testing.T) {
+	// GIVEN
+	down, _ := net.Listen("tcp", "127.0.0.1:0")
+	down.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		chunk, err := readChunk(conn)
+		if err == nil {
+			conn.Write(appendValue(nil, map[string]interface{}{"ack": chunk}))
+			received <- chunk
+		}
+	}()
+	retries := -1
+	output, err := newFluentd(outputs.MothershipConfig{
+		Hosts:      []string{down.Addr().String(), listener.Addr().String()},
+		MaxRetries: &retries,
+	}, settings{Ack: true})
+	assert.Nil(t, err)
+
+	// WHEN
+	err = output.BulkPublish(nil, outputs.Options{}, []common.MapStr{outputstest.PidsEvent(1)})
+
+	// THEN
+	// the events are sent to the aggregator which is up
+	assert.Nil(t, err)
+	select {
+	case chunk := <-received:
+		assert.NotEmpty(t, chunk)
+	case <-time.After(5 * time.Second):
+		t.Fatal("missing message")
+	}
+}
+
+// readChunk reads a message until its chunk id, the last string of the message option
+func readChunk(conn net.Conn) (string, error) {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	message := []byte{}
+	buffer := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return "", err
+		}
+		message = append(message, buffer[:n]...)
+		if i := bytes.Index(message, []byte("\xa5chunk")); i >= 0 {
+			reader := bufio.NewReader(bytes.NewReader(message[i+6:]))
+			if chunk, err := readString(reader); err == nil {
+				return chunk, nil
+			}
+		}
+	}
+}
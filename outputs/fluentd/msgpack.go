@@ -0,0 +1,269 @@
+package fluentd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+/*
+appendValue appends the msgpack encoding of a value, as used by the forward protocol: integers use the smallest
+format, floats are 64 bits, map keys are sorted and times are strings in the libbeat timestamp layout. Other types are
+encoded from their JSON representation.
+*/
+func appendValue(b []byte, value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case int:
+		return appendInt(b, int64(v))
+	case int8:
+		return appendInt(b, int64(v))
+	case int16:
+		return appendInt(b, int64(v))
+	case int32:
+		return appendInt(b, int64(v))
+	case int64:
+		return appendInt(b, v)
+	case uint:
+		return appendUint(b, uint64(v))
+	case uint8:
+		return appendUint(b, uint64(v))
+	case uint16:
+		return appendUint(b, uint64(v))
+	case uint32:
+		return appendUint(b, uint64(v))
+	case uint64:
+		return appendUint(b, v)
+	case float32:
+		return appendFloat(b, float64(v))
+	case float64:
+		return appendFloat(b, v)
+	case string:
+		return appendString(b, v)
+	case common.Time:
+		return appendString(b, time.Time(v).UTC().Format(common.TsLayout))
+	case time.Time:
+		return appendString(b, v.UTC().Format(common.TsLayout))
+	case common.MapStr:
+		return appendMap(b, v)
+	case map[string]interface{}:
+		return appendMap(b, v)
+	case []common.MapStr:
+		b = appendArrayHeader(b, len(v))
+		for _, item := range v {
+			b = appendMap(b, item)
+		}
+		return b
+	case []interface{}:
+		b = appendArrayHeader(b, len(v))
+		for _, item := range v {
+			b = appendValue(b, item)
+		}
+		return b
+	case []string:
+		b = appendArrayHeader(b, len(v))
+		for _, item := range v {
+			b = appendString(b, item)
+		}
+		return b
+	}
+
+	var generic interface{}
+	document, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(document, &generic)
+	}
+	if err != nil {
+		return appendString(b, fmt.Sprint(value))
+	}
+	return appendValue(b, generic)
+}
+
+func appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return appendUint16(b, 0xd1, uint16(v))
+	case v >= math.MinInt32:
+		return appendUint32(b, 0xd2, uint32(v))
+	}
+	return appendUint64(b, 0xd3, uint64(v))
+}
+
+func appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v < 128:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return appendUint16(b, 0xcd, uint16(v))
+	case v <= math.MaxUint32:
+		return appendUint32(b, 0xce, uint32(v))
+	}
+	return appendUint64(b, 0xcf, v)
+}
+
+func appendFloat(b []byte, v float64) []byte {
+	return appendUint64(b, 0xcb, math.Float64bits(v))
+}
+
+func appendString(b []byte, v string) []byte {
+	switch n := len(v); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = appendUint16(b, 0xda, uint16(n))
+	default:
+		b = appendUint32(b, 0xdb, uint32(n))
+	}
+	return append(b, v...)
+}
+
+func appendArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(b, 0xdc, uint16(n))
+	}
+	return appendUint32(b, 0xdd, uint32(n))
+}
+
+func appendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(b, 0xde, uint16(n))
+	}
+	return appendUint32(b, 0xdf, uint32(n))
+}
+
+func appendMap(b []byte, m map[string]interface{}) []byte {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	b = appendMapHeader(b, len(keys))
+	for _, key := range keys {
+		b = appendString(b, key)
+		b = appendValue(b, m[key])
+	}
+	return b
+}
+
+func appendUint16(b []byte, format byte, v uint16) []byte {
+	return append(b, format, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, format byte, v uint32) []byte {
+	return append(b, format, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, format byte, v uint64) []byte {
+	return append(appendUint32(b, format, uint32(v>>32)), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+var errUnexpectedFormat = errors.New("unexpected msgpack format")
+
+/*
+readAck reads an ack response of the forward protocol, a map whose ack key is the chunk id of the acknowledged
+message. Only maps of strings are expected.
+*/
+func readAck(r *bufio.Reader) (string, error) {
+	format, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	var n int
+	switch {
+	case format&0xf0 == 0x80:
+		n = int(format & 0x0f)
+	case format == 0xde:
+		length, err := readLength(r, 2)
+		if err != nil {
+			return "", err
+		}
+		n = length
+	default:
+		return "", errUnexpectedFormat
+	}
+
+	ack := ""
+	for i := 0; i < n; i++ {
+		key, err := readString(r)
+		if err != nil {
+			return "", err
+		}
+		value, err := readString(r)
+		if err != nil {
+			return "", err
+		}
+		if key == "ack" {
+			ack = value
+		}
+	}
+	return ack, nil
+}
+
+func readString(r *bufio.Reader) (string, error) {
+	format, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	var n int
+	switch {
+	case format&0xe0 == 0xa0:
+		n = int(format & 0x1f)
+	case format == 0xd9 || format == 0xc4:
+		n, err = readLength(r, 1)
+	case format == 0xda || format == 0xc5:
+		n, err = readLength(r, 2)
+	case format == 0xdb || format == 0xc6:
+		n, err = readLength(r, 4)
+	default:
+		return "", errUnexpectedFormat
+	}
+	if err != nil {
+		return "", err
+	}
+	value := make([]byte, n)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+func readLength(r *bufio.Reader, size int) (int, error) {
+	length := make([]byte, size)
+	if _, err := io.ReadFull(r, length); err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, b := range length {
+		n = n<<8 | int(b)
+	}
+	return n, nil
+}
@@ -0,0 +1,56 @@
+package fluentd
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendValue(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected []byte
+	}{
+		{nil, []byte{0xc0}},
+		{true, []byte{0xc3}},
+		{1, []byte{0x01}},
+		{-1, []byte{0xff}},
+		{-33, []byte{0xd0, 0xdf}},
+		{-200, []byte{0xd1, 0xff, 0x38}},
+		{int64(-100000), []byte{0xd2, 0xff, 0xfe, 0x79, 0x60}},
+		{uint64(200), []byte{0xcc, 0xc8}},
+		{uint32(70000), []byte{0xce, 0x00, 0x01, 0x11, 0x70}},
+		{uint64(1) << 40, []byte{0xcf, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{1.5, []byte{0xcb, 0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"abc", []byte{0xa3, 'a', 'b', 'c'}},
+		{strings.Repeat("a", 40), append([]byte{0xd9, 40}, strings.Repeat("a", 40)...)},
+		{[]string{"x"}, []byte{0x91, 0xa1, 'x'}},
+		{common.MapStr{"b": 1, "a": "x"}, []byte{0x82, 0xa1, 'a', 0xa1, 'x', 0xa1, 'b', 0x01}},
+		{[]common.MapStr{{"a": false}}, []byte{0x91, 0x81, 0xa1, 'a', 0xc2}},
+		{common.Time(time.Date(2016, 6, 1, 10, 0, 0, 0, time.UTC)), append([]byte{0xb8}, "2016-06-01T10:00:00.000Z"...)},
+		// other types are encoded from their JSON representation
+		{struct{ A int }{2}, []byte{0x81, 0xa1, 'A', 0xcb, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, appendValue(nil, test.value), "%v", test.value)
+	}
+}
+
+func TestReadAck(t *testing.T) {
+	// GIVEN
+	response := []byte{0x81, 0xa3, 'a', 'c', 'k', 0xd9, 4, 'a', 'b', 'c', 'd'}
+
+	// WHEN
+	ack, err := readAck(bufio.NewReader(bytes.NewReader(response)))
+	_, invalidErr := readAck(bufio.NewReader(bytes.NewReader([]byte{0x91, 0xa1, 'a'})))
+
+	// THEN
+	assert.Nil(t, err)
+	assert.Equal(t, "abcd", ack)
+	assert.Equal(t, errUnexpectedFormat, invalidErr)
+}
@@ -0,0 +1,117 @@
+package graphite
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+/*
+client writes the lines to a graphite server. Lines are buffered until they are written: when the connection is
+lost, the lines the failed write did not send are kept and written after the reconnection, before the next events.
+The line cut by the failed write is dropped, as the server discards it with the connection. When the buffer is
+full, the oldest lines are dropped.
+*/
+type client struct {
+	address   string
+	timeout   time.Duration
+	batchSize int
+	maxBuffer int
+	encoder   *pathEncoder
+	conn      net.Conn
+	buffer    []string
+}
+
+func newClient(address string, timeout time.Duration, batchSize int, maxBuffer int, encoder *pathEncoder) *client {
+	return &client{address: address, timeout: timeout, batchSize: batchSize, maxBuffer: maxBuffer, encoder: encoder}
+}
+
+func (c *client) Connect(timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", c.address, timeout)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	return nil
+}
+
+func (c *client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *client) IsConnected() bool {
+	return c.conn != nil
+}
+
+func (c *client) PublishEvent(event common.MapStr) error {
+	_, err := c.PublishEvents([]common.MapStr{event})
+	return err
+}
+
+/*
+PublishEvents buffers the lines of a batch of events and writes the buffer. The batch is accepted even if the write
+fails, as its lines are kept in the buffer: the error makes the connection mode reconnect.
+*/
+func (c *client) PublishEvents(events []common.MapStr) ([]common.MapStr, error) {
+	batch := events
+	if len(batch) > c.batchSize {
+		batch = batch[:c.batchSize]
+	}
+	for _, event := range batch {
+		c.buffer = append(c.buffer, c.encoder.encode(event)...)
+	}
+	if dropped := len(c.buffer) - c.maxBuffer; dropped > 0 {
+		logp.Warn("Graphite buffer full, dropping %v lines", dropped)
+		c.buffer = c.buffer[dropped:]
+	}
+
+	if err := c.flush(); err != nil {
+		return events[len(batch):], err
+	}
+	return events[len(batch):], nil
+}
+
+func (c *client) flush() error {
+	if len(c.buffer) == 0 {
+		return nil
+	}
+	if c.conn == nil {
+		if err := c.Connect(c.timeout); err != nil {
+			return err
+		}
+	}
+	if c.timeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+	written, err := c.conn.Write([]byte(strings.Join(c.buffer, "\n") + "\n"))
+	if err != nil {
+		c.Close()
+		c.dropWritten(written)
+		return err
+	}
+	debug("Wrote %v lines", len(c.buffer))
+	c.buffer = nil
+	return nil
+}
+
+// dropWritten removes from the buffer the lines sent by a partial write of the given number of bytes, and the
+// line it cut
+func (c *client) dropWritten(written int) {
+	sent := 0
+	for sent < len(c.buffer) && written > 0 {
+		if written < len(c.buffer[sent])+1 {
+			logp.Warn("Graphite connection lost while writing, dropping the partially written line")
+		}
+		written -= len(c.buffer[sent]) + 1
+		sent++
+	}
+	c.buffer = c.buffer[sent:]
+}
@@ -0,0 +1,187 @@
+/*
+Package graphite defines the graphite output, which writes the numeric fields of the events with the Graphite
+plaintext protocol over TCP, one line per field:
+
+	dockbeat.docker1.web.memory.usage 12345678 1464775200
+
+The metric paths are built from a template, {prefix}.{host}.{container}.{type}.{metric} by default, whose
+placeholders are replaced by sanitized values: characters other than letters, digits, - and _ are replaced by _.
+The metric is the dotted path of the field in the event type section, prefixed by the network name for net events.
+Aggregate events use aggregate_<scope>[_<value>] as container.
+*/
+package graphite
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/mode"
+
+	dockbeatoutputs "github.com/ingensi/dockbeat/outputs"
+)
+
+const (
+	defaultPort     = 2003
+	defaultPrefix   = "dockbeat"
+	defaultTemplate = "{prefix}.{host}.{container}.{type}.{metric}"
+	defaultBuffer   = 10000
+)
+
+var debug = logp.MakeDebug("graphite")
+
+var invalidPathChars = regexp.MustCompile("[^a-zA-Z0-9_-]")
+
+func init() {
+	outputs.RegisterOutputPlugin("graphite", graphiteOutputPlugin{})
+}
+
+// settings build the metric paths and size the buffer of the graphite clients
+type settings struct {
+	Prefix string
+	// Template of the metric paths, with {prefix}, {host}, {image}, {container}, {type} and {metric} placeholders
+	Template string
+	// Buffer is the maximum number of lines kept while graphite is unreachable
+	Buffer int
+}
+
+type graphiteOutputPlugin struct{}
+
+func (p graphiteOutputPlugin) NewOutput(config *outputs.MothershipConfig, topologyExpire int) (outputs.Outputer, error) {
+	s := settings{}
+	if err := dockbeatoutputs.ReadSettings("graphite", &s); err != nil {
+		return nil, err
+	}
+	return newGraphite(*config, s)
+}
+
+type graphite struct {
+	mode mode.ConnectionMode
+}
+
+func newGraphite(config outputs.MothershipConfig, s settings) (*graphite, error) {
+	if s.Prefix == "" {
+		s.Prefix = defaultPrefix
+	}
+	if s.Template == "" {
+		s.Template = defaultTemplate
+	}
+	if s.Buffer <= 0 {
+		s.Buffer = defaultBuffer
+	}
+	encoder := &pathEncoder{prefix: s.Prefix, template: s.Template}
+	timeout := dockbeatoutputs.Timeout(config)
+	batchSize := dockbeatoutputs.BatchSize(config)
+
+	clients, err := mode.MakeClients(config, func(host string) (mode.ProtocolClient, error) {
+		return newClient(dockbeatoutputs.WithPort(host, config.Port, defaultPort), timeout, batchSize, s.Buffer, encoder), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m, err := dockbeatoutputs.NewConnectionMode(config, clients, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &graphite{mode: m}, nil
+}
+
+func (g *graphite) PublishEvent(signaler outputs.Signaler, opts outputs.Options, event common.MapStr) error {
+	return g.mode.PublishEvent(signaler, opts, event)
+}
+
+// BulkPublish implements the BulkOutputer interface, events are written in batches of bulk_max_size events
+func (g *graphite) BulkPublish(signaler outputs.Signaler, opts outputs.Options, events []common.MapStr) error {
+	return g.mode.PublishEvents(signaler, opts, events)
+}
+
+// pathEncoder encodes the events in plaintext lines
+type pathEncoder struct {
+	prefix   string
+	template string
+}
+
+// encode returns the lines of the numeric fields of an event
+func (e *pathEncoder) encode(event common.MapStr) []string {
+	eventType, _ := event["type"].(string)
+	metrics := dockbeatoutputs.Metrics(event)
+	if eventType == "" || len(metrics) == 0 {
+		debug("Dropping %v event without numeric fields", eventType)
+		return nil
+	}
+
+	container, _ := event["containerName"].(string)
+	image, _ := event["containerImage"].(string)
+	section, _ := event[eventType].(common.MapStr)
+	metricPrefix := ""
+	switch eventType {
+	case "net":
+		network, _ := section["name"].(string)
+		metricPrefix = sanitize(network) + "."
+	case "aggregate":
+		scope, _ := section["scope"].(string)
+		container = "aggregate_" + scope
+		if value, ok := section["value"].(string); ok {
+			container += "_" + value
+		}
+	}
+
+	replacer := strings.NewReplacer(
+		"{prefix}", e.prefix,
+		"{host}", sanitize(dockbeatoutputs.Hostname(event)),
+		"{container}", sanitize(container),
+		"{image}", sanitize(image),
+		"{type}", sanitize(eventType),
+	)
+	path := replacer.Replace(e.template)
+	timestamp := " " + strconv.FormatInt(dockbeatoutputs.Timestamp(event).Unix(), 10)
+
+	lines := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		names := strings.Split(metric.Name, ".")
+		for i := range names {
+			names[i] = sanitize(names[i])
+		}
+		metricPath := strings.Replace(path, "{metric}", metricPrefix+strings.Join(names, "."), -1)
+		lines = append(lines, cleanPath(metricPath)+" "+formatValue(metric.Value)+timestamp)
+	}
+	return lines
+}
+
+// sanitize replaces the characters which are not allowed in a path component
+func sanitize(component string) string {
+	return invalidPathChars.ReplaceAllString(component, "_")
+}
+
+// cleanPath removes the empty components of a path, e.g. the container of events without container
+func cleanPath(path string) string {
+	components := strings.Split(path, ".")
+	output := components[:0]
+	for _, component := range components {
+		if component != "" {
+			output = append(output, component)
+		}
+	}
+	return strings.Join(output, ".")
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return "0"
+}
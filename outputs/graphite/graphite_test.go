@@ -0,0 +1,168 @@
+package graphite
+
+import (
+	"bufio"
+	"errors"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/ingensi/dockbeat/outputs/outputstest"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPathEncoderEncode(t *testing.T) {
+	// GIVEN
+	encoder := &pathEncoder{prefix: "servers", template: defaultTemplate}
+	event := outputstest.NetEvent()
+	event["containerName"] = "web.1"
+	event["beat"] = common.MapStr{"name": "docker1", "hostname": "docker1.example.com"}
+
+	// WHEN
+	lines := encoder.encode(event)
+
+	// THEN
+	// components are sanitized, net metrics are prefixed by the network name
+	assert.Equal(t, []string{
+		"servers.docker1_example_com.web_1.net.eth0.rxBytes 1024 1464775200",
+		"servers.docker1_example_com.web_1.net.eth0.rxBytes_ps 12.5 1464775200",
+	}, lines)
+}
+
+func TestPathEncoderEncodeTemplate(t *testing.T) {
+	// GIVEN
+	encoder := &pathEncoder{prefix: "dockbeat", template: "{prefix}.{image}.{container}.{metric}"}
+	aggregate := common.MapStr{
+		"@timestamp": common.Time(time.Unix(1464775200, 0)),
+		"type":       "aggregate",
+		"aggregate":  common.MapStr{"scope": "host", "containers": 3},
+	}
+	event := common.MapStr{
+		"@timestamp":     common.Time(time.Unix(1464775200, 0)),
+		"type":           "pids",
+		"containerID":    "container_id",
+		"containerName":  "web",
+		"containerImage": "library/nginx:1.10",
+		"pids":           common.MapStr{"current": uint64(4)},
+	}
+
+	// WHEN
+	aggregateLines := encoder.encode(aggregate)
+	lines := encoder.encode(event)
+
+	// THEN
+	// empty components are removed
+	assert.Equal(t, []string{"dockbeat.aggregate_host.containers 3 1464775200"}, aggregateLines)
+	assert.Equal(t, []string{"dockbeat.library_nginx_1_10.web.current 4 1464775200"}, lines)
+}
+
+/*
+TestClientPublishEventsReconnect simulates a graphite server closing the connection: the lines of the failed write
+are written after the reconnection.
+*/
+func TestClientPublishEventsReconnect(t *testing.T) {
+	// GIVEN
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+		}
+	}()
+	output, err := newGraphite(outputs.MothershipConfig{Hosts: []string{listener.Addr().String()}}, settings{})
+	assert.Nil(t, err)
+	client := newClient(listener.Addr().String(), time.Second, 10, 10, &pathEncoder{prefix: "dockbeat", template: defaultTemplate})
+
+	// WHEN
+	err = output.PublishEvent(nil, outputs.Options{}, outputstest.PidsEvent(1))
+	assert.Nil(t, err)
+	client.Connect(time.Second)
+	// the connection is closed by the client side to simulate a lost connection
+	client.conn.Close()
+	_, failedErr := client.PublishEvents([]common.MapStr{outputstest.PidsEvent(2)})
+	_, err = client.PublishEvents([]common.MapStr{outputstest.PidsEvent(3)})
+
+	// THEN
+	assert.NotNil(t, failedErr)
+	assert.Nil(t, err)
+	received := []string{}
+	for i := 0; i < 3; i++ {
+		select {
+		case line := <-lines:
+			received = append(received, line)
+		case <-time.After(5 * time.Second):
+			t.Fatal("missing lines")
+		}
+	}
+	assert.Contains(t, received, "dockbeat.docker1.web.pids.current 1 1464775200")
+	assert.Contains(t, received, "dockbeat.docker1.web.pids.current 2 1464775200")
+	assert.Contains(t, received, "dockbeat.docker1.web.pids.current 3 1464775200")
+}
+
+func TestClientFlushPartialWrite(t *testing.T) {
+	// GIVEN
+	client := newClient("localhost:2003", time.Second, 10, 10, &pathEncoder{prefix: "dockbeat", template: defaultTemplate})
+	client.buffer = []string{"a 1 1464775200", "b 2 1464775200", "c 3 1464775200"}
+	// the connection is lost after the first line and the beginning of the second one
+	client.conn = &failingConn{accepted: len("a 1 1464775200\nb 2")}
+
+	// WHEN
+	err := client.flush()
+
+	// THEN
+	// the written line and the cut one are not written again
+	assert.NotNil(t, err)
+	assert.False(t, client.IsConnected())
+	assert.Equal(t, []string{"c 3 1464775200"}, client.buffer)
+}
+
+func TestClientPublishEventsBufferFull(t *testing.T) {
+	// GIVEN
+	// nothing listens on the address
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	address := listener.Addr().String()
+	listener.Close()
+	client := newClient(address, time.Second, 10, 2, &pathEncoder{prefix: "dockbeat", template: defaultTemplate})
+
+	// WHEN
+	rest, err := client.PublishEvents([]common.MapStr{outputstest.PidsEvent(1), outputstest.PidsEvent(2), outputstest.PidsEvent(3)})
+
+	// THEN
+	// the oldest lines are dropped
+	assert.NotNil(t, err)
+	assert.Empty(t, rest)
+	assert.Equal(t, []string{"dockbeat.docker1.web.pids.current 2 1464775200", "dockbeat.docker1.web.pids.current 3 1464775200"}, client.buffer)
+}
+
+// failingConn is a connection which fails after having written the accepted number of bytes
+type failingConn struct {
+	net.Conn
+	accepted int
+}
+
+func (c *failingConn) Write(b []byte) (int, error) {
+	if len(b) > c.accepted {
+		return c.accepted, errors.New("connection reset by peer")
+	}
+	return len(b), nil
+}
+
+func (c *failingConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *failingConn) Close() error {
+	return nil
+}
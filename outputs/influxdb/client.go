@@ -0,0 +1,186 @@
+package influxdb
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// udpPacketSize is the maximum size of the UDP packets, to avoid IP fragmentation on common networks
+const udpPacketSize = 1400
+
+// httpClient writes the events with the /write endpoint of the HTTP API
+type httpClient struct {
+	url       string
+	username  string
+	password  string
+	client    *http.Client
+	batchSize int
+	encoder   *lineEncoder
+	connected bool
+}
+
+func newHTTPClient(scheme string, host string, database string, username string, password string, tlsConfig *tls.Config,
+	timeout time.Duration, batchSize int, encoder *lineEncoder) *httpClient {
+
+	query := url.Values{"db": {database}, "precision": {"ns"}}
+	return &httpClient{
+		url:      scheme + "://" + host + "/write?" + query.Encode(),
+		username: username,
+		password: password,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+		},
+		batchSize: batchSize,
+		encoder:   encoder,
+	}
+}
+
+// Connect only marks the client as connected, the /write requests open their connections
+func (c *httpClient) Connect(timeout time.Duration) error {
+	c.connected = true
+	return nil
+}
+
+func (c *httpClient) Close() error {
+	c.connected = false
+	return nil
+}
+
+func (c *httpClient) IsConnected() bool {
+	return c.connected
+}
+
+func (c *httpClient) PublishEvent(event common.MapStr) error {
+	_, err := c.PublishEvents([]common.MapStr{event})
+	return err
+}
+
+/*
+PublishEvents writes a batch of events, the remaining ones are returned to be published by the next call. Batches
+rejected by the server (e.g. with a field type conflict) are dropped, as retrying them would fail again.
+*/
+func (c *httpClient) PublishEvents(events []common.MapStr) ([]common.MapStr, error) {
+	batch := events
+	if len(batch) > c.batchSize {
+		batch = batch[:c.batchSize]
+	}
+	lines := c.encoder.encodeLines(batch)
+	if len(lines) == 0 {
+		return events[len(batch):], nil
+	}
+
+	request, err := http.NewRequest("POST", c.url, strings.NewReader(strings.Join(lines, "\n")+"\n"))
+	if err != nil {
+		return events, err
+	}
+	request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if c.username != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		c.connected = false
+		return events, err
+	}
+	defer response.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+
+	switch {
+	case response.StatusCode/100 == 2:
+		debug("Wrote %v events", len(lines))
+	case response.StatusCode == http.StatusBadRequest:
+		logp.Err("Dropping %v events rejected by influxdb: %s", len(lines), body)
+	default:
+		return events, fmt.Errorf("influxdb write failed with status %v: %s", response.Status, body)
+	}
+	return events[len(batch):], nil
+}
+
+// udpClient writes the events to the UDP service, packing as many lines as possible in each packet
+type udpClient struct {
+	address   string
+	timeout   time.Duration
+	batchSize int
+	encoder   *lineEncoder
+	conn      net.Conn
+}
+
+func newUDPClient(address string, timeout time.Duration, batchSize int, encoder *lineEncoder) *udpClient {
+	return &udpClient{address: address, timeout: timeout, batchSize: batchSize, encoder: encoder}
+}
+
+func (c *udpClient) Connect(timeout time.Duration) error {
+	conn, err := net.DialTimeout("udp", c.address, timeout)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	return nil
+}
+
+func (c *udpClient) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *udpClient) IsConnected() bool {
+	return c.conn != nil
+}
+
+func (c *udpClient) PublishEvent(event common.MapStr) error {
+	_, err := c.PublishEvents([]common.MapStr{event})
+	return err
+}
+
+// PublishEvents writes a batch of events. UDP has no acknowledgement, only local write errors are reported.
+func (c *udpClient) PublishEvents(events []common.MapStr) ([]common.MapStr, error) {
+	batch := events
+	if len(batch) > c.batchSize {
+		batch = batch[:c.batchSize]
+	}
+
+	var packet bytes.Buffer
+	for _, line := range c.encoder.encodeLines(batch) {
+		if packet.Len() > 0 && packet.Len()+len(line)+1 > udpPacketSize {
+			if err := c.write(packet.Bytes()); err != nil {
+				return events, err
+			}
+			packet.Reset()
+		}
+		packet.WriteString(line + "\n")
+	}
+	if packet.Len() > 0 {
+		if err := c.write(packet.Bytes()); err != nil {
+			return events, err
+		}
+	}
+	return events[len(batch):], nil
+}
+
+func (c *udpClient) write(packet []byte) error {
+	if c.timeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+	if _, err := c.conn.Write(packet); err != nil {
+		c.Close()
+		return err
+	}
+	return nil
+}
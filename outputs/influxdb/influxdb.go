@@ -0,0 +1,267 @@
+/*
+Package influxdb defines the influxdb output, which writes the events in the InfluxDB line protocol over HTTP or UDP:
+
+	memory,host=docker1,image=nginx,name=web failcnt=0i,limit=2147483648i,usage=12345678i,usage_p=0.0057 1464775200000000000
+
+The measurement is the event type, the tags are the dockbeat host, the container name and image, the network
+name for net events, the scope, key and value for aggregate events and the configured container labels. The fields
+are the numeric fields of the event type section. Events without numeric fields (e.g. log events) are dropped.
+*/
+package influxdb
+
+import (
+	"crypto/tls"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/mode"
+
+	dockbeatoutputs "github.com/ingensi/dockbeat/outputs"
+)
+
+const (
+	defaultHTTPPort = 8086
+	defaultUDPPort  = 8089
+	defaultDatabase = "dockbeat"
+)
+
+var debug = logp.MakeDebug("influxdb")
+
+func init() {
+	outputs.RegisterOutputPlugin("influxdb", influxdbOutputPlugin{})
+}
+
+// settings of the influxdb section, besides the hosts, credentials and tls ones
+type settings struct {
+	// Database is the database the points are written to, with the HTTP protocol
+	Database string
+	// Labels are the container labels written as tags
+	Labels []string
+}
+
+type influxdbOutputPlugin struct{}
+
+func (p influxdbOutputPlugin) NewOutput(config *outputs.MothershipConfig, topologyExpire int) (outputs.Outputer, error) {
+	s := settings{}
+	if err := dockbeatoutputs.ReadSettings("influxdb", &s); err != nil {
+		return nil, err
+	}
+	return newInfluxdb(*config, s)
+}
+
+type influxdb struct {
+	mode mode.ConnectionMode
+}
+
+func newInfluxdb(config outputs.MothershipConfig, s settings) (*influxdb, error) {
+	if s.Database == "" {
+		s.Database = defaultDatabase
+	}
+	encoder := &lineEncoder{labels: s.Labels}
+	timeout := dockbeatoutputs.Timeout(config)
+	batchSize := dockbeatoutputs.BatchSize(config)
+
+	var newClient func(string) (mode.ProtocolClient, error)
+	switch config.Protocol {
+	case "", "http", "https":
+		var tlsConfig *tls.Config
+		if config.TLS != nil {
+			var err error
+			if tlsConfig, err = outputs.LoadTLSConfig(config.TLS); err != nil {
+				return nil, err
+			}
+		}
+		scheme := "http"
+		if config.Protocol == "https" || tlsConfig != nil {
+			scheme = "https"
+		}
+		newClient = func(host string) (mode.ProtocolClient, error) {
+			return newHTTPClient(scheme, dockbeatoutputs.WithPort(host, config.Port, defaultHTTPPort), s.Database, config.Username,
+				config.Password, tlsConfig, timeout, batchSize, encoder), nil
+		}
+	case "udp":
+		newClient = func(host string) (mode.ProtocolClient, error) {
+			return newUDPClient(dockbeatoutputs.WithPort(host, config.Port, defaultUDPPort), timeout, batchSize, encoder), nil
+		}
+	default:
+		return nil, errors.New("unknown influxdb protocol " + config.Protocol + ", expected http, https or udp")
+	}
+
+	clients, err := mode.MakeClients(config, newClient)
+	if err != nil {
+		return nil, err
+	}
+	m, err := dockbeatoutputs.NewConnectionMode(config, clients, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &influxdb{mode: m}, nil
+}
+
+func (i *influxdb) PublishEvent(signaler outputs.Signaler, opts outputs.Options, event common.MapStr) error {
+	return i.mode.PublishEvent(signaler, opts, event)
+}
+
+// BulkPublish implements the BulkOutputer interface, events are written in batches of bulk_max_size lines
+func (i *influxdb) BulkPublish(signaler outputs.Signaler, opts outputs.Options, events []common.MapStr) error {
+	return i.mode.PublishEvents(signaler, opts, events)
+}
+
+/*
+lineEncoder encodes events in the line protocol. InfluxDB rejects a field whose type changes, so the fields seen as
+floats are remembered: their integer values (e.g. a rate of 0) are written as floats too.
+*/
+type lineEncoder struct {
+	sync.Mutex
+	labels []string
+	// floats are the float fields, by measurement
+	floats map[string]map[string]bool
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	keyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// encode returns the line of an event, or "" when the event has no numeric field
+func (e *lineEncoder) encode(event common.MapStr) string {
+	eventType, _ := event["type"].(string)
+	metrics := dockbeatoutputs.Metrics(event)
+	if eventType == "" || len(metrics) == 0 {
+		debug("Dropping %v event without numeric fields", eventType)
+		return ""
+	}
+
+	tags := map[string]string{
+		"host": dockbeatoutputs.Hostname(event),
+	}
+	if image, ok := event["containerImage"].(string); ok {
+		tags["image"] = image
+	}
+	if name, ok := event["containerName"].(string); ok {
+		tags["name"] = name
+	}
+	section, _ := event[eventType].(common.MapStr)
+	if eventType == "net" {
+		tags["network"], _ = section["name"].(string)
+	}
+	if eventType == "aggregate" {
+		for _, field := range []string{"scope", "key", "value"} {
+			tags[field], _ = section[field].(string)
+		}
+	}
+	for _, label := range e.labels {
+		if value, ok := dockbeatoutputs.Label(event, label); ok {
+			tags[label] = value
+		}
+	}
+
+	keys := []string{}
+	for key, value := range tags {
+		// empty tag values are not allowed
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	line := measurementEscaper.Replace(eventType)
+	for _, key := range keys {
+		line += "," + keyEscaper.Replace(key) + "=" + keyEscaper.Replace(tags[key])
+	}
+	e.Lock()
+	floats := e.floats[eventType]
+	for i, metric := range metrics {
+		separator := ","
+		if i == 0 {
+			separator = " "
+		}
+		value := formatValue(metric.Value)
+		if floats[metric.Name] {
+			value = formatFloat(metric.Value)
+		}
+		line += separator + keyEscaper.Replace(metric.Name) + "=" + value
+	}
+	e.Unlock()
+	return line + " " + strconv.FormatInt(dockbeatoutputs.Timestamp(event).UnixNano(), 10)
+}
+
+// formatValue formats integers with the i suffix, so that they are stored as integers
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v) + "i"
+	case int32:
+		return strconv.FormatInt(int64(v), 10) + "i"
+	case int64:
+		return strconv.FormatInt(v, 10) + "i"
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10) + "i"
+	case uint64:
+		if v > math.MaxInt64 {
+			return strconv.FormatFloat(float64(v), 'g', -1, 64)
+		}
+		return strconv.FormatUint(v, 10) + "i"
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return "0"
+}
+
+// formatFloat formats a numeric value as a float
+func formatFloat(value interface{}) string {
+	switch v := value.(type) {
+	case int:
+		return strconv.FormatFloat(float64(v), 'g', -1, 64)
+	case int32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 64)
+	case int64:
+		return strconv.FormatFloat(float64(v), 'g', -1, 64)
+	case uint32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 64)
+	case uint64:
+		return strconv.FormatFloat(float64(v), 'g', -1, 64)
+	}
+	return formatValue(value)
+}
+
+// learnFloats remembers the float fields of the events
+func (e *lineEncoder) learnFloats(events []common.MapStr) {
+	e.Lock()
+	defer e.Unlock()
+	if e.floats == nil {
+		e.floats = map[string]map[string]bool{}
+	}
+	for _, event := range events {
+		eventType, _ := event["type"].(string)
+		for _, metric := range dockbeatoutputs.Metrics(event) {
+			if _, ok := metric.Value.(float64); !ok {
+				continue
+			}
+			if e.floats[eventType] == nil {
+				e.floats[eventType] = map[string]bool{}
+			}
+			e.floats[eventType][metric.Name] = true
+		}
+	}
+}
+
+// encodeLines encodes the events which have numeric fields, one line each. The float fields of the whole batch are
+// known before encoding, so that a field has the same type in all the lines.
+func (e *lineEncoder) encodeLines(events []common.MapStr) []string {
+	e.learnFloats(events)
+	lines := make([]string, 0, len(events))
+	for _, event := range events {
+		if line := e.encode(event); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
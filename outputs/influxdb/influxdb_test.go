@@ -0,0 +1,157 @@
+package influxdb
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/ingensi/dockbeat/outputs/outputstest"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineEncoderEncode(t *testing.T) {
+	// GIVEN
+	encoder := &lineEncoder{labels: []string{"com.example.team", "missing"}}
+
+	// WHEN
+	line := encoder.encode(outputstest.NetEvent())
+
+	// THEN
+	assert.Equal(t, `net,com.example.team=core\ team,host=docker1,image=nginx,name=web,network=eth0 `+
+		`rxBytes=1024i,rxBytes_ps=12.5 1464775200000000000`, line)
+}
+
+func TestLineEncoderEncodeWithoutNumericFields(t *testing.T) {
+	// GIVEN
+	encoder := &lineEncoder{}
+
+	// WHEN
+	line := encoder.encode(common.MapStr{"type": "log", "log": common.MapStr{"level": "info", "message": "message"}})
+
+	// THEN
+	assert.Equal(t, "", line)
+}
+
+func TestLineEncoderEncodeLinesFieldTypes(t *testing.T) {
+	// GIVEN
+	encoder := &lineEncoder{}
+	first := outputstest.NetEvent()
+	first["net"] = common.MapStr{"name": "eth0", "rxBytes": uint64(1024), "rxBytes_ps": 0}
+
+	// WHEN
+	lines := encoder.encodeLines([]common.MapStr{first, outputstest.NetEvent()})
+	next := encoder.encode(first)
+
+	// THEN
+	// a float field is written as a float in all the lines, even with an integer value
+	assert.Equal(t, []string{
+		"net,host=docker1,image=nginx,name=web,network=eth0 rxBytes=1024i,rxBytes_ps=0 1464775200000000000",
+		"net,host=docker1,image=nginx,name=web,network=eth0 rxBytes=1024i,rxBytes_ps=12.5 1464775200000000000",
+	}, lines)
+	assert.Equal(t, lines[0], next)
+}
+
+func TestHTTPClientPublishEvents(t *testing.T) {
+	// GIVEN
+	requests := []*http.Request{}
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	batchSize := 2
+	output, err := newInfluxdb(outputs.MothershipConfig{
+		Hosts:       []string{strings.TrimPrefix(server.URL, "http://")},
+		Username:    "user",
+		Password:    "secret",
+		BulkMaxSize: &batchSize,
+	}, settings{Database: "metrics"})
+	assert.Nil(t, err)
+
+	// WHEN
+	err = output.BulkPublish(nil, outputs.Options{}, []common.MapStr{outputstest.NetEvent(), outputstest.NetEvent(), outputstest.NetEvent()})
+
+	// THEN
+	// events are written in batches of bulk_max_size lines
+	assert.Nil(t, err)
+	assert.Len(t, requests, 2)
+	assert.Equal(t, "metrics", requests[0].URL.Query().Get("db"))
+	assert.Equal(t, "ns", requests[0].URL.Query().Get("precision"))
+	username, password, _ := requests[0].BasicAuth()
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "secret", password)
+	assert.Equal(t, 2, strings.Count(bodies[0], "\n"))
+	assert.Equal(t, 1, strings.Count(bodies[1], "\n"))
+}
+
+func TestHTTPClientPublishEventsServerError(t *testing.T) {
+	// GIVEN
+	statuses := []int{http.StatusServiceUnavailable, http.StatusBadRequest}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[0])
+		statuses = statuses[1:]
+	}))
+	defer server.Close()
+	client := newHTTPClient("http", strings.TrimPrefix(server.URL, "http://"), "dockbeat", "", "", nil,
+		time.Second, 10, &lineEncoder{})
+	events := []common.MapStr{outputstest.NetEvent()}
+
+	// WHEN
+	unavailable, unavailableErr := client.PublishEvents(events)
+	rejected, rejectedErr := client.PublishEvents(events)
+
+	// THEN
+	// unavailable servers are retried, rejected events are dropped
+	assert.NotNil(t, unavailableErr)
+	assert.Equal(t, events, unavailable)
+	assert.Nil(t, rejectedErr)
+	assert.Empty(t, rejected)
+}
+
+func TestUDPClientPublishEvents(t *testing.T) {
+	// GIVEN
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	output, err := newInfluxdb(outputs.MothershipConfig{
+		Hosts:    []string{listener.LocalAddr().String()},
+		Protocol: "udp",
+	}, settings{})
+	assert.Nil(t, err)
+	events := []common.MapStr{}
+	for i := 0; i < 20; i++ {
+		events = append(events, outputstest.NetEvent())
+	}
+
+	// WHEN
+	err = output.BulkPublish(nil, outputs.Options{}, events)
+
+	// THEN
+	// lines are packed in packets of at most 1400 bytes
+	assert.Nil(t, err)
+	lines := 0
+	buffer := make([]byte, 65536)
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for lines < 20 {
+		n, _, err := listener.ReadFrom(buffer)
+		if !assert.Nil(t, err) {
+			break
+		}
+		assert.True(t, n <= udpPacketSize)
+		lines += strings.Count(string(buffer[:n]), "\n")
+	}
+	assert.Equal(t, 20, lines)
+}
+
+func TestNewInfluxdbUnknownProtocol(t *testing.T) {
+	_, err := newInfluxdb(outputs.MothershipConfig{Hosts: []string{"localhost"}, Protocol: "tcp"}, settings{})
+	assert.NotNil(t, err)
+}
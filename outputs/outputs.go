@@ -0,0 +1,199 @@
+/*
+Package outputs contains the helpers shared by the dockbeat outputs, which are registered in the libbeat outputs
+registry by their own packages (outputs/influxdb, ...) when they are imported by the main package.
+
+The libbeat outputs.MothershipConfig only contains the settings of the libbeat outputs: the settings specific to a
+dockbeat output are read from its section of the configuration file with ReadSettings.
+*/
+package outputs
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/cfgfile"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/mode"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	DefaultTimeout    = 30 * time.Second
+	DefaultMaxRetries = 3
+	DefaultBatchSize  = 1000
+)
+
+// the publish retries wait 1 second, doubled at each failure up to 60 seconds
+var (
+	waitRetry    = time.Second
+	maxWaitRetry = 60 * time.Second
+)
+
+// Metric is a numeric field of an event, Name being its dotted path in the section of the event type
+type Metric struct {
+	Name  string
+	Value interface{}
+}
+
+// outputSections are the sections of the output configuration, read once for all the outputs
+var outputSections struct {
+	sync.Mutex
+	m map[string]interface{}
+}
+
+/*
+ReadSettings reads the section of the given output in the configuration file into settings, a structure of the
+settings which are not part of the libbeat outputs.MothershipConfig. The libbeat outputs only receive their
+MothershipConfig, which drops the other settings: the output section of the configuration file is read on the first
+call, and shared by the outputs.
+*/
+func ReadSettings(name string, settings interface{}) error {
+	outputSections.Lock()
+	defer outputSections.Unlock()
+	if outputSections.m == nil {
+		config := struct {
+			Output map[string]interface{}
+		}{}
+		if err := cfgfile.Read(&config, ""); err != nil {
+			return err
+		}
+		outputSections.m = config.Output
+		if outputSections.m == nil {
+			outputSections.m = map[string]interface{}{}
+		}
+	}
+
+	section, err := yaml.Marshal(outputSections.m[name])
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(section, settings)
+}
+
+/*
+NewConnectionMode creates the connection mode of the output clients, one per host, like the libbeat outputs: a
+single connection mode for one host, a load balancing mode when loadbalance is set and a fail over mode otherwise.
+Failed publications are retried max_retries times (3 by default, forever when negative) with an exponential backoff.
+*/
+func NewConnectionMode(config outputs.MothershipConfig, clients []mode.ProtocolClient, timeout time.Duration) (mode.ConnectionMode, error) {
+	sendRetries := DefaultMaxRetries
+	if config.MaxRetries != nil {
+		sendRetries = *config.MaxRetries
+	}
+	maxAttempts := sendRetries + 1
+	if sendRetries < 0 {
+		maxAttempts = 0
+	}
+
+	if len(clients) == 1 {
+		return mode.NewSingleConnectionMode(clients[0], maxAttempts, waitRetry, timeout, maxWaitRetry)
+	}
+	if config.LoadBalance != nil && *config.LoadBalance {
+		return mode.NewLoadBalancerMode(clients, maxAttempts, waitRetry, timeout, maxWaitRetry)
+	}
+	return mode.NewFailOverConnectionMode(clients, maxAttempts, waitRetry, timeout)
+}
+
+// Timeout returns the timeout setting of an output, 30 seconds by default
+func Timeout(config outputs.MothershipConfig) time.Duration {
+	if config.Timeout != 0 {
+		return time.Duration(config.Timeout) * time.Second
+	}
+	return DefaultTimeout
+}
+
+// BatchSize returns the bulk_max_size setting of an output, 1000 by default
+func BatchSize(config outputs.MothershipConfig) int {
+	if config.BulkMaxSize != nil && *config.BulkMaxSize > 0 {
+		return *config.BulkMaxSize
+	}
+	return DefaultBatchSize
+}
+
+// WithPort adds the port setting of an output, or its default port, to the hosts without port
+func WithPort(host string, port int, defaultPort int) string {
+	if strings.Contains(host, ":") {
+		return host
+	}
+	if port == 0 {
+		port = defaultPort
+	}
+	return host + ":" + strconv.Itoa(port)
+}
+
+/*
+Metrics returns the numeric fields of the section named after the event type (e.g. memory for memory events),
+sorted by name. Nested sections are flattened with dotted names, arrays and non finite values are skipped.
+*/
+func Metrics(event common.MapStr) []Metric {
+	eventType, _ := event["type"].(string)
+	section, ok := event[eventType].(common.MapStr)
+	if !ok {
+		return nil
+	}
+	metrics := []Metric{}
+	addMetrics(&metrics, "", section)
+	sort.Sort(byName(metrics))
+	return metrics
+}
+
+func addMetrics(metrics *[]Metric, prefix string, section common.MapStr) {
+	for key, value := range section {
+		switch v := value.(type) {
+		case common.MapStr:
+			addMetrics(metrics, prefix+key+".", v)
+		case float64:
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				*metrics = append(*metrics, Metric{prefix + key, v})
+			}
+		case float32:
+			if !math.IsNaN(float64(v)) && !math.IsInf(float64(v), 0) {
+				*metrics = append(*metrics, Metric{prefix + key, float64(v)})
+			}
+		case int, int32, int64, uint32, uint64:
+			*metrics = append(*metrics, Metric{prefix + key, v})
+		}
+	}
+}
+
+type byName []Metric
+
+func (m byName) Len() int           { return len(m) }
+func (m byName) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+func (m byName) Less(i, j int) bool { return m[i].Name < m[j].Name }
+
+// Timestamp returns the @timestamp of an event, or now when it has none
+func Timestamp(event common.MapStr) time.Time {
+	if timestamp, ok := event["@timestamp"].(common.Time); ok {
+		return time.Time(timestamp)
+	}
+	return time.Now()
+}
+
+// Hostname returns the hostname of the beat which published the event
+func Hostname(event common.MapStr) string {
+	if beat, ok := event["beat"].(common.MapStr); ok {
+		if hostname, ok := beat["hostname"].(string); ok {
+			return hostname
+		}
+	}
+	return ""
+}
+
+// Label returns the value of a container label of an event, the key having its dots replaced by underscores
+func Label(event common.MapStr, key string) (string, bool) {
+	labels, _ := event["containerLabels"].([]common.MapStr)
+	key = strings.Replace(key, ".", "_", -1)
+	for _, label := range labels {
+		if label["key"] == key {
+			value, ok := label["value"].(string)
+			return value, ok
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,50 @@
+package outputs
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	// GIVEN
+	event := common.MapStr{
+		"type": "memory",
+		"memory": common.MapStr{
+			"usage":   uint64(1024),
+			"usage_p": math.NaN(),
+			"limited": true,
+			"events":  common.MapStr{"oom": uint64(1)},
+			"devices": []common.MapStr{{"read": uint64(1)}},
+		},
+		"containerName": "web",
+	}
+
+	// WHEN
+	metrics := Metrics(event)
+
+	// THEN
+	// non numeric fields, arrays and non finite values are skipped
+	assert.Equal(t, []Metric{{"events.oom", uint64(1)}, {"usage", uint64(1024)}}, metrics)
+}
+
+func TestLabel(t *testing.T) {
+	// GIVEN
+	event := common.MapStr{"containerLabels": []common.MapStr{{"key": "com_example_team", "value": "core"}}}
+
+	// WHEN
+	value, ok := Label(event, "com.example.team")
+	_, missing := Label(event, "env")
+
+	// THEN
+	assert.True(t, ok)
+	assert.Equal(t, "core", value)
+	assert.False(t, missing)
+}
+
+func TestWithPort(t *testing.T) {
+	assert.Equal(t, "influx:8086", WithPort("influx", 0, 8086))
+	assert.Equal(t, "influx:9999", WithPort("influx", 9999, 8086))
+	assert.Equal(t, "influx:1234", WithPort("influx:1234", 9999, 8086))
+}
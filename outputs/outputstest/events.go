@@ -0,0 +1,38 @@
+// Package outputstest contains the events used by the tests of the dockbeat outputs.
+package outputstest
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Timestamp is the @timestamp of the events, 2016-06-01T10:00:00Z
+var Timestamp = common.Time(time.Date(2016, 6, 1, 10, 0, 0, 0, time.UTC))
+
+// PidsEvent returns a pids event of the web container of the nginx image published by the docker1 host
+func PidsEvent(current uint64) common.MapStr {
+	return common.MapStr{
+		"@timestamp":     Timestamp,
+		"type":           "pids",
+		"containerID":    "container_id",
+		"containerName":  "web",
+		"containerImage": "nginx",
+		"beat":           common.MapStr{"name": "docker1", "hostname": "docker1"},
+		"pids":           common.MapStr{"current": current},
+	}
+}
+
+// NetEvent returns a net event of the eth0 network of the web container of the nginx image, which has a label with a space
+func NetEvent() common.MapStr {
+	return common.MapStr{
+		"@timestamp":      Timestamp,
+		"type":            "net",
+		"containerID":     "container_id",
+		"containerName":   "web",
+		"containerImage":  "nginx",
+		"containerLabels": []common.MapStr{{"key": "com_example_team", "value": "core team"}},
+		"beat":            common.MapStr{"name": "docker1", "hostname": "docker1"},
+		"net":             common.MapStr{"name": "eth0", "rxBytes": uint64(1024), "rxBytes_ps": 12.5},
+	}
+}
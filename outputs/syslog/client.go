@@ -0,0 +1,120 @@
+package syslog
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+/*
+netClient sends the messages to a syslog relay. The unix network is a unix datagram socket, or a unix stream socket
+when the relay doesn't listen to datagrams, like the log/syslog package.
+*/
+type netClient struct {
+	network   string
+	address   string
+	timeout   time.Duration
+	batchSize int
+	encoder   *messageEncoder
+	conn      net.Conn
+	framing   func([]byte) []byte
+}
+
+func newNetClient(network string, address string, timeout time.Duration, batchSize int, encoder *messageEncoder) *netClient {
+	return &netClient{network: network, address: address, timeout: timeout, batchSize: batchSize, encoder: encoder}
+}
+
+func (c *netClient) Connect(timeout time.Duration) error {
+	network := c.network
+	if network == "unix" {
+		network = "unixgram"
+	}
+	conn, err := net.DialTimeout(network, c.address, timeout)
+	if err != nil && c.network == "unix" {
+		network = "unix"
+		conn, err = net.DialTimeout(network, c.address, timeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch network {
+	case "tcp":
+		c.framing = octetCounting
+	case "unix":
+		c.framing = newLine
+	default:
+		c.framing = nil
+	}
+	c.conn = conn
+	return nil
+}
+
+func (c *netClient) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *netClient) IsConnected() bool {
+	return c.conn != nil
+}
+
+func (c *netClient) PublishEvent(event common.MapStr) error {
+	_, err := c.PublishEvents([]common.MapStr{event})
+	return err
+}
+
+/*
+PublishEvents sends a batch of events, the remaining ones are returned to be published by the next call. Over
+stream sockets, the messages of the batch are written at once.
+*/
+func (c *netClient) PublishEvents(events []common.MapStr) ([]common.MapStr, error) {
+	batch := events
+	if len(batch) > c.batchSize {
+		batch = batch[:c.batchSize]
+	}
+	if c.timeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+
+	stream := []byte{}
+	for i, event := range batch {
+		message, err := c.encoder.encode(event)
+		if err != nil {
+			logp.Err("Dropping event which can't be encoded: %v", err)
+			continue
+		}
+		if c.framing != nil {
+			stream = append(stream, c.framing(message)...)
+			continue
+		}
+		if _, err := c.conn.Write(message); err != nil {
+			c.Close()
+			return events[i:], err
+		}
+	}
+	if len(stream) > 0 {
+		if _, err := c.conn.Write(stream); err != nil {
+			c.Close()
+			return events, err
+		}
+	}
+	debug("Sent %v events", len(batch))
+	return events[len(batch):], nil
+}
+
+// octetCounting frames a message with its length, as defined by RFC 6587
+func octetCounting(message []byte) []byte {
+	return append([]byte(strconv.Itoa(len(message))+" "), message...)
+}
+
+func newLine(message []byte) []byte {
+	return append(message, '\n')
+}
@@ -0,0 +1,216 @@
+/*
+Package syslog defines the syslog output, which sends the events to a syslog relay in the RFC 5424 format over UDP,
+TCP or a unix socket:
+
+	<30>1 2016-06-01T10:00:00.000Z docker1 dockbeat 42 memory [dockbeat@32473 containerID="..." containerName="web" image="nginx" type="memory"] {"@timestamp":...}
+
+The message id is the event type, the structured data contains the key fields of the event and the message is the
+JSON document of the event. The severity of log events follows their level, other events are informational.
+
+Messages are sent one per datagram over UDP and unix datagram sockets, with the octet counting framing of RFC 6587
+over TCP and terminated by a new line over unix stream sockets.
+*/
+package syslog
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/mode"
+
+	dockbeatoutputs "github.com/ingensi/dockbeat/outputs"
+)
+
+const (
+	defaultPort     = 514
+	defaultFacility = "daemon"
+	defaultAppName  = "dockbeat"
+	// defaultSDID uses the private enterprise number reserved for documentation by RFC 5612
+	defaultSDID = "dockbeat@32473"
+)
+
+// severities of RFC 5424
+const (
+	severityError   = 3
+	severityWarning = 4
+	severityInfo    = 6
+	severityDebug   = 7
+)
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7, "uucp": 8,
+	"cron": 9, "authpriv": 10, "ftp": 11, "local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20,
+	"local5": 21, "local6": 22, "local7": 23,
+}
+
+var debug = logp.MakeDebug("syslog")
+
+func init() {
+	outputs.RegisterOutputPlugin("syslog", syslogOutputPlugin{})
+}
+
+// settings of the RFC 5424 message header and structured data
+type settings struct {
+	Facility string
+	// App is the APP-NAME of the messages
+	App string
+	// SDID is the id of the structured data element
+	SDID string
+}
+
+type syslogOutputPlugin struct{}
+
+func (p syslogOutputPlugin) NewOutput(config *outputs.MothershipConfig, topologyExpire int) (outputs.Outputer, error) {
+	s := settings{}
+	if err := dockbeatoutputs.ReadSettings("syslog", &s); err != nil {
+		return nil, err
+	}
+	return newSyslog(*config, s)
+}
+
+type syslog struct {
+	mode mode.ConnectionMode
+}
+
+func newSyslog(config outputs.MothershipConfig, s settings) (*syslog, error) {
+	if s.Facility == "" {
+		s.Facility = defaultFacility
+	}
+	facility, ok := facilities[s.Facility]
+	if !ok {
+		return nil, errors.New("unknown syslog facility " + s.Facility)
+	}
+	if s.App == "" {
+		s.App = defaultAppName
+	}
+	if s.SDID == "" {
+		s.SDID = defaultSDID
+	}
+	encoder := &messageEncoder{facility: facility, app: s.App, sdid: s.SDID, procID: strconv.Itoa(os.Getpid())}
+	timeout := dockbeatoutputs.Timeout(config)
+	batchSize := dockbeatoutputs.BatchSize(config)
+
+	var newClient func(string) (mode.ProtocolClient, error)
+	switch config.Protocol {
+	case "", "udp", "tcp":
+		network := config.Protocol
+		if network == "" {
+			network = "udp"
+		}
+		newClient = func(host string) (mode.ProtocolClient, error) {
+			return newNetClient(network, dockbeatoutputs.WithPort(host, config.Port, defaultPort), timeout, batchSize, encoder), nil
+		}
+	case "unix":
+		newClient = func(path string) (mode.ProtocolClient, error) {
+			return newNetClient("unix", path, timeout, batchSize, encoder), nil
+		}
+	default:
+		return nil, errors.New("unknown syslog protocol " + config.Protocol + ", expected udp, tcp or unix")
+	}
+
+	clients, err := mode.MakeClients(config, newClient)
+	if err != nil {
+		return nil, err
+	}
+	m, err := dockbeatoutputs.NewConnectionMode(config, clients, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &syslog{mode: m}, nil
+}
+
+func (s *syslog) PublishEvent(signaler outputs.Signaler, opts outputs.Options, event common.MapStr) error {
+	return s.mode.PublishEvent(signaler, opts, event)
+}
+
+// BulkPublish implements the BulkOutputer interface, events are sent in batches of bulk_max_size events
+func (s *syslog) BulkPublish(signaler outputs.Signaler, opts outputs.Options, events []common.MapStr) error {
+	return s.mode.PublishEvents(signaler, opts, events)
+}
+
+// messageEncoder encodes the events in RFC 5424 messages
+type messageEncoder struct {
+	facility int
+	app      string
+	sdid     string
+	procID   string
+}
+
+var paramEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// encode returns the message of an event, or an error when the event can't be encoded in JSON
+func (e *messageEncoder) encode(event common.MapStr) ([]byte, error) {
+	eventType, _ := event["type"].(string)
+	document, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	params := [][2]string{}
+	for _, field := range []string{"containerID", "containerName"} {
+		if value, ok := event[field].(string); ok {
+			params = append(params, [2]string{field, value})
+		}
+	}
+	if image, ok := event["containerImage"].(string); ok && image != "" {
+		params = append(params, [2]string{"image", image})
+	}
+	level := ""
+	if eventType == "log" {
+		section, _ := event["log"].(common.MapStr)
+		level, _ = section["level"].(string)
+		params = append(params, [2]string{"level", level})
+	}
+	params = append(params, [2]string{"type", eventType})
+
+	structuredData := "[" + e.sdid
+	for _, param := range params {
+		structuredData += " " + param[0] + `="` + paramEscaper.Replace(param[1]) + `"`
+	}
+	structuredData += "]"
+
+	header := "<" + strconv.Itoa(e.facility*8+severity(level)) + ">1 " +
+		dockbeatoutputs.Timestamp(event).UTC().Format(common.TsLayout) + " " +
+		headerField(dockbeatoutputs.Hostname(event), 255) + " " +
+		headerField(e.app, 48) + " " +
+		headerField(e.procID, 128) + " " +
+		headerField(eventType, 32) + " " +
+		structuredData + " "
+	return append([]byte(header), document...), nil
+}
+
+// severity returns the severity of a log event level, as written by the beater, informational for other events
+func severity(level string) int {
+	switch level {
+	case "error":
+		return severityError
+	case "warning":
+		return severityWarning
+	case "debug", "trace":
+		return severityDebug
+	}
+	return severityInfo
+}
+
+// headerField returns a header field of at most max printable characters, or the nil value - when it is empty
+func headerField(value string, max int) string {
+	field := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, value)
+	if len(field) > max {
+		field = field[:max]
+	}
+	if field == "" {
+		return "-"
+	}
+	return field
+}
@@ -0,0 +1,147 @@
+package syslog
+
+import (
+	"bufio"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/ingensi/dockbeat/outputs/outputstest"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageEncoderEncode(t *testing.T) {
+	// GIVEN
+	encoder := &messageEncoder{facility: 3, app: "dockbeat", sdid: defaultSDID, procID: "42"}
+	event := outputstest.PidsEvent(4)
+	event["containerName"] = `web "1"`
+
+	// WHEN
+	message, err := encoder.encode(event)
+
+	// THEN
+	// structured data values are escaped
+	assert.Nil(t, err)
+	assert.Equal(t, `<30>1 2016-06-01T10:00:00.000Z docker1 dockbeat 42 pids [dockbeat@32473 containerID="container_id" `+
+		`containerName="web \"1\"" image="nginx" type="pids"] {"@timestamp":"2016-06-01T10:00:00.000Z",`+
+		`"beat":{"hostname":"docker1","name":"docker1"},"containerID":"container_id","containerImage":"nginx",`+
+		`"containerName":"web \"1\"",`+
+		`"pids":{"current":4},"type":"pids"}`, string(message))
+}
+
+func TestMessageEncoderEncodeLog(t *testing.T) {
+	// GIVEN
+	encoder := &messageEncoder{facility: 16, app: "dockbeat", sdid: defaultSDID, procID: "42"}
+	event := common.MapStr{
+		"@timestamp": common.Time(time.Date(2016, 6, 1, 10, 0, 0, 0, time.UTC)),
+		"type":       "log",
+		"log":        common.MapStr{"level": "warning", "message": "Ignoring tick(s)"},
+	}
+
+	// WHEN
+	message, err := encoder.encode(event)
+
+	// THEN
+	// the severity follows the level, empty header fields are nil values
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(message),
+		`<132>1 2016-06-01T10:00:00.000Z - dockbeat 42 log [dockbeat@32473 level="warning" type="log"] {`))
+}
+
+func TestSeverity(t *testing.T) {
+	assert.Equal(t, severityError, severity("error"))
+	assert.Equal(t, severityWarning, severity("warning"))
+	assert.Equal(t, severityInfo, severity("info"))
+	assert.Equal(t, severityDebug, severity("debug"))
+	assert.Equal(t, severityInfo, severity(""))
+}
+
+func TestSyslogBulkPublishTCP(t *testing.T) {
+	// GIVEN
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		content := []byte{}
+		buffer := make([]byte, 4096)
+		for {
+			n, err := conn.Read(buffer)
+			content = append(content, buffer[:n]...)
+			if err != nil || strings.Count(string(content), "<30>1") == 2 {
+				break
+			}
+		}
+		received <- string(content)
+	}()
+	output, err := newSyslog(outputs.MothershipConfig{Hosts: []string{listener.Addr().String()}, Protocol: "tcp"}, settings{})
+	assert.Nil(t, err)
+
+	// WHEN
+	err = output.BulkPublish(nil, outputs.Options{}, []common.MapStr{outputstest.PidsEvent(4), outputstest.PidsEvent(4)})
+
+	// THEN
+	// messages are framed with their length
+	assert.Nil(t, err)
+	content := <-received
+	reader := bufio.NewReader(strings.NewReader(content))
+	for i := 0; i < 2; i++ {
+		length, err := reader.ReadString(' ')
+		assert.Nil(t, err)
+		n, err := strconv.Atoi(strings.TrimSpace(length))
+		assert.Nil(t, err)
+		message := make([]byte, n)
+		_, err = io.ReadFull(reader, message)
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(string(message), "<30>1 "))
+		assert.True(t, strings.HasSuffix(string(message), `"type":"pids"}`))
+	}
+}
+
+func TestSyslogBulkPublishUnix(t *testing.T) {
+	// GIVEN
+	dir, err := ioutil.TempDir("", "dockbeat")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log")
+	conn, err := net.ListenPacket("unixgram", path)
+	assert.Nil(t, err)
+	defer conn.Close()
+	output, err := newSyslog(outputs.MothershipConfig{Hosts: []string{path}, Protocol: "unix"}, settings{Facility: "local0"})
+	assert.Nil(t, err)
+
+	// WHEN
+	err = output.BulkPublish(nil, outputs.Options{}, []common.MapStr{outputstest.PidsEvent(4), outputstest.PidsEvent(4)})
+
+	// THEN
+	// one message per datagram
+	assert.Nil(t, err)
+	buffer := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for i := 0; i < 2; i++ {
+		n, _, err := conn.ReadFrom(buffer)
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(string(buffer[:n]), "<134>1 "))
+		assert.True(t, strings.HasSuffix(string(buffer[:n]), `"type":"pids"}`))
+	}
+}
+
+func TestNewSyslogInvalidSettings(t *testing.T) {
+	_, protocolErr := newSyslog(outputs.MothershipConfig{Hosts: []string{"localhost"}, Protocol: "http"}, settings{})
+	assert.NotNil(t, protocolErr)
+	_, facilityErr := newSyslog(outputs.MothershipConfig{Hosts: []string{"localhost"}}, settings{Facility: "local9"})
+	assert.NotNil(t, facilityErr)
+}
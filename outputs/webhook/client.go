@@ -0,0 +1,144 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// client posts the events to an endpoint
+type client struct {
+	url       string
+	settings  settings
+	username  string
+	password  string
+	client    *http.Client
+	batchSize int
+	connected bool
+}
+
+func newClient(url string, s settings, username string, password string, tlsConfig *tls.Config,
+	timeout time.Duration, batchSize int) *client {
+
+	return &client{
+		url:      url,
+		settings: s,
+		username: username,
+		password: password,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+		},
+		batchSize: batchSize,
+	}
+}
+
+// Connect only marks the client as connected, a failed post marks it disconnected until the connection mode retries
+func (c *client) Connect(timeout time.Duration) error {
+	c.connected = true
+	return nil
+}
+
+func (c *client) Close() error {
+	c.connected = false
+	return nil
+}
+
+func (c *client) IsConnected() bool {
+	return c.connected
+}
+
+func (c *client) PublishEvent(event common.MapStr) error {
+	_, err := c.PublishEvents([]common.MapStr{event})
+	return err
+}
+
+/*
+PublishEvents posts a batch of events, the remaining ones are returned to be published by the next call. Batches
+whose content is rejected by the endpoint (400, 413 and 422 statuses) are dropped, as retrying them would fail
+again. Other errors, including authentication ones (401 and 403) which are fixed by renewing the credentials, are
+retried.
+*/
+func (c *client) PublishEvents(events []common.MapStr) ([]common.MapStr, error) {
+	batch := events
+	if len(batch) > c.batchSize {
+		batch = batch[:c.batchSize]
+	}
+	body, count := c.encode(batch)
+	if count == 0 {
+		return events[len(batch):], nil
+	}
+
+	request, err := http.NewRequest("POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return events, err
+	}
+	if c.settings.Format == formatNDJSON {
+		request.Header.Set("Content-Type", "application/x-ndjson")
+	} else {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range c.settings.Headers {
+		request.Header.Set(name, value)
+	}
+	if c.settings.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.settings.Token)
+	} else if c.username != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		c.connected = false
+		return events, err
+	}
+	defer response.Body.Close()
+	responseBody, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+
+	switch {
+	case response.StatusCode/100 == 2:
+		debug("Posted %v events", count)
+	case rejectedContent[response.StatusCode]:
+		logp.Err("Dropping %v events rejected by %v with status %v: %s", count, c.url, response.Status, responseBody)
+	default:
+		return events, fmt.Errorf("webhook post failed with status %v: %s", response.Status, responseBody)
+	}
+	return events[len(batch):], nil
+}
+
+// rejectedContent are the statuses of requests whose content can't be accepted by the endpoint
+var rejectedContent = map[int]bool{
+	http.StatusBadRequest:            true,
+	http.StatusRequestEntityTooLarge: true,
+	http.StatusUnprocessableEntity:   true,
+}
+
+// encode returns the body of a batch and the number of encoded events. Events which can't be encoded are dropped.
+func (c *client) encode(events []common.MapStr) ([]byte, int) {
+	documents := make([][]byte, 0, len(events))
+	for _, event := range events {
+		document, err := json.Marshal(event)
+		if err != nil {
+			logp.Err("Dropping event which can't be encoded: %v", err)
+			continue
+		}
+		documents = append(documents, document)
+	}
+	if len(documents) == 0 {
+		return nil, 0
+	}
+
+	if c.settings.Format == formatNDJSON {
+		return append(bytes.Join(documents, []byte("\n")), '\n'), len(documents)
+	}
+	body := append([]byte{'['}, bytes.Join(documents, []byte{','})...)
+	return append(body, ']'), len(documents)
+}
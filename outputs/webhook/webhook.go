@@ -0,0 +1,116 @@
+/*
+Package webhook defines the webhook output, which POSTs batches of events to HTTP endpoints, either as a JSON array
+of events (format json, the default) or as one JSON document per line (format ndjson).
+
+The hosts are the URLs of the endpoints. Hosts without scheme use http, or https when the protocol is https or a
+TLS configuration is set, and the path setting. Requests can be authenticated with basic authentication (username
+and password) or with a bearer token, and carry custom headers.
+*/
+package webhook
+
+import (
+	"crypto/tls"
+	"errors"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/mode"
+
+	dockbeatoutputs "github.com/ingensi/dockbeat/outputs"
+)
+
+const (
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+var debug = logp.MakeDebug("webhook")
+
+func init() {
+	outputs.RegisterOutputPlugin("webhook", webhookOutputPlugin{})
+}
+
+// settings of the request body and headers, the url and basic credentials come from the hosts, path and username
+type settings struct {
+	// Format is json or ndjson
+	Format  string
+	Headers map[string]string
+	// Token is sent as bearer token in the Authorization header
+	Token string
+}
+
+type webhookOutputPlugin struct{}
+
+func (p webhookOutputPlugin) NewOutput(config *outputs.MothershipConfig, topologyExpire int) (outputs.Outputer, error) {
+	s := settings{}
+	if err := dockbeatoutputs.ReadSettings("webhook", &s); err != nil {
+		return nil, err
+	}
+	return newWebhook(*config, s)
+}
+
+type webhook struct {
+	mode mode.ConnectionMode
+}
+
+func newWebhook(config outputs.MothershipConfig, s settings) (*webhook, error) {
+	switch s.Format {
+	case "":
+		s.Format = formatJSON
+	case formatJSON, formatNDJSON:
+	default:
+		return nil, errors.New("unknown webhook format " + s.Format + ", expected json or ndjson")
+	}
+	if s.Token != "" && config.Username != "" {
+		return nil, errors.New("webhook username and token can't be both set")
+	}
+
+	var tlsConfig *tls.Config
+	if config.TLS != nil {
+		var err error
+		if tlsConfig, err = outputs.LoadTLSConfig(config.TLS); err != nil {
+			return nil, err
+		}
+	}
+	scheme := "http"
+	if config.Protocol == "https" || tlsConfig != nil {
+		scheme = "https"
+	}
+	timeout := dockbeatoutputs.Timeout(config)
+	batchSize := dockbeatoutputs.BatchSize(config)
+
+	clients, err := mode.MakeClients(config, func(host string) (mode.ProtocolClient, error) {
+		return newClient(makeURL(scheme, host, config.Path), s, config.Username, config.Password, tlsConfig,
+			timeout, batchSize), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m, err := dockbeatoutputs.NewConnectionMode(config, clients, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook{mode: m}, nil
+}
+
+func (w *webhook) PublishEvent(signaler outputs.Signaler, opts outputs.Options, event common.MapStr) error {
+	return w.mode.PublishEvent(signaler, opts, event)
+}
+
+// BulkPublish implements the BulkOutputer interface, events are posted in batches of bulk_max_size events
+func (w *webhook) BulkPublish(signaler outputs.Signaler, opts outputs.Options, events []common.MapStr) error {
+	return w.mode.PublishEvents(signaler, opts, events)
+}
+
+// makeURL returns the URL of a host, adding the scheme and the path to hosts without scheme
+func makeURL(scheme string, host string, path string) string {
+	if strings.Contains(host, "://") {
+		return host
+	}
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return scheme + "://" + host + path
+}
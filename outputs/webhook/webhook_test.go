@@ -0,0 +1,158 @@
+package webhook
+
+import (
+	"encoding/json"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/ingensi/dockbeat/outputs/outputstest"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookBulkPublishJSON(t *testing.T) {
+	// GIVEN
+	requests := []*http.Request{}
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+	batchSize := 2
+	output, err := newWebhook(outputs.MothershipConfig{
+		Hosts:       []string{strings.TrimPrefix(server.URL, "http://")},
+		Path:        "events",
+		Username:    "user",
+		Password:    "secret",
+		BulkMaxSize: &batchSize,
+	}, settings{Headers: map[string]string{"X-Source": "dockbeat"}})
+	assert.Nil(t, err)
+
+	// WHEN
+	err = output.BulkPublish(nil, outputs.Options{}, []common.MapStr{outputstest.PidsEvent(1), outputstest.PidsEvent(2), outputstest.PidsEvent(3)})
+
+	// THEN
+	// events are posted in batches of bulk_max_size events
+	assert.Nil(t, err)
+	assert.Len(t, requests, 2)
+	assert.Equal(t, "/events", requests[0].URL.Path)
+	assert.Equal(t, "application/json", requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, "dockbeat", requests[0].Header.Get("X-Source"))
+	username, password, _ := requests[0].BasicAuth()
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "secret", password)
+	documents := []common.MapStr{}
+	assert.Nil(t, json.Unmarshal([]byte(bodies[0]), &documents))
+	assert.Len(t, documents, 2)
+	assert.Equal(t, "pids", documents[0]["type"])
+	assert.Equal(t, "2016-06-01T10:00:00.000Z", documents[0]["@timestamp"])
+}
+
+func TestWebhookBulkPublishNDJSON(t *testing.T) {
+	// GIVEN
+	var request *http.Request
+	body := ""
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		request = r
+		body = string(content)
+	}))
+	defer server.Close()
+	output, err := newWebhook(outputs.MothershipConfig{
+		Hosts: []string{server.URL + "/ingest"},
+		TLS:   &outputs.TLSConfig{Insecure: true},
+	}, settings{Format: "ndjson", Token: "t0k3n"})
+	assert.Nil(t, err)
+	nan := outputstest.PidsEvent(3)
+	nan["pids"] = common.MapStr{"current_p": math.NaN()}
+
+	// WHEN
+	err = output.BulkPublish(nil, outputs.Options{}, []common.MapStr{outputstest.PidsEvent(1), nan, outputstest.PidsEvent(2)})
+
+	// THEN
+	// events which can't be encoded are dropped
+	assert.Nil(t, err)
+	assert.Equal(t, "/ingest", request.URL.Path)
+	assert.Equal(t, "application/x-ndjson", request.Header.Get("Content-Type"))
+	assert.Equal(t, "Bearer t0k3n", request.Header.Get("Authorization"))
+	lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+	assert.Len(t, lines, 2)
+	document := common.MapStr{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &document))
+	assert.Equal(t, map[string]interface{}{"current": float64(2)}, document["pids"])
+}
+
+func TestClientPublishEventsServerError(t *testing.T) {
+	// GIVEN
+	statuses := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusBadRequest}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[0])
+		statuses = statuses[1:]
+	}))
+	defer server.Close()
+	client := newClient(server.URL, settings{Format: formatJSON}, "", "", nil, time.Second, 10)
+	events := []common.MapStr{outputstest.PidsEvent(1)}
+
+	// WHEN
+	unavailable, unavailableErr := client.PublishEvents(events)
+	throttled, throttledErr := client.PublishEvents(events)
+	rejected, rejectedErr := client.PublishEvents(events)
+
+	// THEN
+	// unavailable and throttling endpoints are retried, rejected events are dropped
+	assert.NotNil(t, unavailableErr)
+	assert.Equal(t, events, unavailable)
+	assert.NotNil(t, throttledErr)
+	assert.Equal(t, events, throttled)
+	assert.Nil(t, rejectedErr)
+	assert.Empty(t, rejected)
+}
+
+func TestClientPublishEventsAuthenticationError(t *testing.T) {
+	// GIVEN
+	statuses := []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusUnprocessableEntity}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[0])
+		statuses = statuses[1:]
+	}))
+	defer server.Close()
+	client := newClient(server.URL, settings{Format: formatJSON, Token: "expired"}, "", "", nil, time.Second, 10)
+	events := []common.MapStr{outputstest.PidsEvent(1)}
+
+	// WHEN
+	unauthorized, unauthorizedErr := client.PublishEvents(events)
+	forbidden, forbiddenErr := client.PublishEvents(events)
+	rejected, rejectedErr := client.PublishEvents(events)
+
+	// THEN
+	// authentication errors are retried until the credentials are fixed, invalid content is dropped
+	assert.NotNil(t, unauthorizedErr)
+	assert.Equal(t, events, unauthorized)
+	assert.NotNil(t, forbiddenErr)
+	assert.Equal(t, events, forbidden)
+	assert.Nil(t, rejectedErr)
+	assert.Empty(t, rejected)
+}
+
+func TestNewWebhookInvalidSettings(t *testing.T) {
+	config := outputs.MothershipConfig{Hosts: []string{"localhost"}}
+	_, formatErr := newWebhook(config, settings{Format: "xml"})
+	assert.NotNil(t, formatErr)
+
+	config.Username = "user"
+	_, authErr := newWebhook(config, settings{Token: "t0k3n"})
+	assert.NotNil(t, authErr)
+}
+
+func TestMakeURL(t *testing.T) {
+	assert.Equal(t, "http://ingest:8080/events", makeURL("http", "ingest:8080", "events"))
+	assert.Equal(t, "https://ingest", makeURL("https", "ingest", ""))
+	assert.Equal(t, "http://ingest/v1/events", makeURL("https", "http://ingest/v1/events", "events"))
+}
@@ -0,0 +1,238 @@
+// Package procfs reads the metrics of container processes from the host proc filesystem, for the metrics
+// docker and cgroups do not provide.
+package procfs
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const DefaultRoot = "/proc"
+
+// TCP states, as defined in the kernel include/net/tcp_states.h
+var tcpStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+}
+
+// TCPStates lists all TCP states
+var TCPStates = []string{
+	"ESTABLISHED", "SYN_SENT", "SYN_RECV", "FIN_WAIT1", "FIN_WAIT2", "TIME_WAIT",
+	"CLOSE", "CLOSE_WAIT", "LAST_ACK", "LISTEN", "CLOSING",
+}
+
+// Reader reads files of the proc filesystem
+type Reader struct {
+	// Root is the proc filesystem mount point, e.g. /host/proc when running inside a container
+	Root string
+}
+
+// Socket is an entry of the /proc/<pid>/net/tcp, tcp6, udp or udp6 files, Protocol being the file name.
+// UDP sockets use the same states as TCP ones.
+type Socket struct {
+	Protocol      string
+	LocalAddress  net.IP
+	LocalPort     int
+	RemoteAddress net.IP
+	RemotePort    int
+	State         string
+}
+
+func NewReader(root string) *Reader {
+	if root == "" {
+		root = DefaultRoot
+	}
+	return &Reader{Root: root}
+}
+
+/*
+TCPSockets returns the IPv4 and IPv6 TCP sockets of the network namespace of the given process.
+
+As all the processes of a container share the same network namespace, the container main process PID
+(State.Pid of InspectContainer) gives the sockets of the whole container.
+*/
+func (r *Reader) TCPSockets(pid int) ([]Socket, error) {
+	return r.sockets(pid, "tcp", "tcp6")
+}
+
+/*
+ListeningSockets returns the TCP sockets in the LISTEN state and the unconnected UDP sockets (bound to a local
+port without remote address) of the network namespace of the given process.
+*/
+func (r *Reader) ListeningSockets(pid int) ([]Socket, error) {
+	sockets, err := r.sockets(pid, "tcp", "tcp6", "udp", "udp6")
+	if err != nil {
+		return nil, err
+	}
+
+	listening := []Socket{}
+	for _, socket := range sockets {
+		if socket.State == "LISTEN" || (strings.HasPrefix(socket.Protocol, "udp") && socket.RemotePort == 0) {
+			listening = append(listening, socket)
+		}
+	}
+	return listening, nil
+}
+
+func (r *Reader) sockets(pid int, protocols ...string) ([]Socket, error) {
+	sockets := []Socket{}
+	for _, protocol := range protocols {
+		path := filepath.Join(r.Root, strconv.Itoa(pid), "net", protocol)
+		protocolSockets, err := readSockets(path, protocol)
+		if err != nil {
+			return nil, err
+		}
+		sockets = append(sockets, protocolSockets...)
+	}
+	return sockets, nil
+}
+
+/*
+readSockets parses a /proc/<pid>/net socket file, whose first line is a header:
+
+	sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+	 0: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 12345 1 ...
+*/
+func readSockets(path string, protocol string) ([]Socket, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sockets := []Socket{}
+	lines := strings.Split(string(content), "\n")
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		socket := Socket{Protocol: protocol, State: tcpStates[fields[3]]}
+		if socket.LocalAddress, socket.LocalPort, err = parseAddress(fields[1]); err != nil {
+			return nil, fmt.Errorf("invalid local address in %v: %v", path, err)
+		}
+		if socket.RemoteAddress, socket.RemotePort, err = parseAddress(fields[2]); err != nil {
+			return nil, fmt.Errorf("invalid remote address in %v: %v", path, err)
+		}
+		sockets = append(sockets, socket)
+	}
+	return sockets, nil
+}
+
+// parseAddress parses "0100007F:0CEA" addresses. IPs are made of 32 bits words in host byte order (little
+// endian on supported architectures), ports are in network byte order.
+func parseAddress(address string) (net.IP, int, error) {
+	parts := strings.Split(address, ":")
+	if len(parts) != 2 {
+		return nil, 0, errors.New("malformed address " + address)
+	}
+
+	words, err := hex.DecodeString(parts[0])
+	if err != nil || (len(words) != net.IPv4len && len(words) != net.IPv6len) {
+		return nil, 0, errors.New("malformed ip " + parts[0])
+	}
+	ip := make(net.IP, len(words))
+	for i := 0; i < len(words); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = words[i+3], words[i+2], words[i+1], words[i]
+	}
+
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return nil, 0, errors.New("malformed port " + parts[1])
+	}
+	return ip, int(port), nil
+}
+
+// FileDescriptors is the file descriptors usage of a process
+type FileDescriptors struct {
+	Pid  int
+	Open uint64
+	// Limit is the soft RLIMIT_NOFILE of the process, 0 when unlimited
+	Limit uint64
+}
+
+// FileDescriptors returns the number of open file descriptors of the given process (entries of /proc/<pid>/fd)
+// and its open files limit (from /proc/<pid>/limits).
+func (r *Reader) FileDescriptors(pid int) (FileDescriptors, error) {
+	output := FileDescriptors{Pid: pid}
+
+	dir, err := os.Open(filepath.Join(r.Root, strconv.Itoa(pid), "fd"))
+	if err != nil {
+		return output, err
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return output, err
+	}
+	output.Open = uint64(len(names))
+
+	output.Limit, err = r.openFilesLimit(pid)
+	return output, err
+}
+
+/*
+openFilesLimit parses the soft limit of the "Max open files" line of /proc/<pid>/limits:
+
+	Limit                     Soft Limit           Hard Limit           Units
+	Max open files            1024                 4096                 files
+*/
+func (r *Reader) openFilesLimit(pid int) (uint64, error) {
+	content, err := ioutil.ReadFile(filepath.Join(r.Root, strconv.Itoa(pid), "limits"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if !strings.HasPrefix(line, "Max open files") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "Max open files"))
+		if len(fields) == 0 {
+			break
+		}
+		if fields[0] == "unlimited" {
+			return 0, nil
+		}
+		return strconv.ParseUint(fields[0], 10, 64)
+	}
+	return 0, errors.New("no open files limit for process " + strconv.Itoa(pid))
+}
+
+/*
+MemTotal returns the total memory of the host in bytes, from the MemTotal line of /proc/meminfo:
+
+	MemTotal:        8167848 kB
+*/
+func (r *Reader) MemTotal() (uint64, error) {
+	content, err := ioutil.ReadFile(filepath.Join(r.Root, "meminfo"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return kb * 1024, nil
+	}
+	return 0, errors.New("no MemTotal in meminfo")
+}
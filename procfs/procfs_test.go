@@ -0,0 +1,141 @@
+package procfs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func TestReaderTCPSockets(t *testing.T) {
+	// GIVEN
+	reader := NewReader("testdata")
+
+	// WHEN
+	sockets, err := reader.TCPSockets(1234)
+
+	// THEN
+	assert.Nil(t, err)
+	assert.Equal(t, 8, len(sockets))
+	assert.Equal(t, Socket{
+		Protocol:      "tcp",
+		LocalAddress:  net.IPv4(127, 0, 0, 1).To4(),
+		LocalPort:     8080,
+		RemoteAddress: net.IPv4zero.To4(),
+		RemotePort:    0,
+		State:         "LISTEN",
+	}, sockets[1])
+	assert.Equal(t, Socket{
+		Protocol:      "tcp",
+		LocalAddress:  net.IPv4(192, 168, 1, 2).To4(),
+		LocalPort:     80,
+		RemoteAddress: net.IPv4(192, 168, 1, 1).To4(),
+		RemotePort:    54321,
+		State:         "ESTABLISHED",
+	}, sockets[2])
+	assert.Equal(t, "TIME_WAIT", sockets[4].State)
+	assert.Equal(t, "CLOSE_WAIT", sockets[5].State)
+
+	// IPv6 sockets
+	assert.Equal(t, "tcp6", sockets[6].Protocol)
+	assert.Equal(t, net.IPv6zero, sockets[6].LocalAddress)
+	assert.Equal(t, 443, sockets[6].LocalPort)
+	assert.Equal(t, "192.168.1.2", sockets[7].LocalAddress.String())
+	assert.Equal(t, "ESTABLISHED", sockets[7].State)
+}
+
+func TestReaderTCPSocketsUnknownProcess(t *testing.T) {
+	// GIVEN
+	reader := NewReader("testdata")
+
+	// WHEN
+	sockets, err := reader.TCPSockets(4321)
+
+	// THEN
+	assert.NotNil(t, err)
+	assert.Nil(t, sockets)
+}
+
+func TestNewReaderDefaultRoot(t *testing.T) {
+	assert.Equal(t, DefaultRoot, NewReader("").Root)
+}
+
+func TestParseAddressMalformed(t *testing.T) {
+	_, _, err := parseAddress("0100007F")
+	assert.NotNil(t, err)
+	_, _, err = parseAddress("01007F:0050")
+	assert.NotNil(t, err)
+	_, _, err = parseAddress("0100007F:XYZ")
+	assert.NotNil(t, err)
+}
+
+func TestReaderListeningSockets(t *testing.T) {
+	// GIVEN
+	reader := NewReader("testdata")
+
+	// WHEN
+	sockets, err := reader.ListeningSockets(1234)
+
+	// THEN
+	// tcp sockets in the LISTEN state and the bound udp socket, the connected udp socket is ignored
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(sockets))
+	assert.Equal(t, "tcp", sockets[0].Protocol)
+	assert.Equal(t, 80, sockets[0].LocalPort)
+	assert.Equal(t, "tcp", sockets[1].Protocol)
+	assert.Equal(t, 8080, sockets[1].LocalPort)
+	assert.Equal(t, "tcp6", sockets[2].Protocol)
+	assert.Equal(t, 443, sockets[2].LocalPort)
+	assert.Equal(t, "udp", sockets[3].Protocol)
+	assert.Equal(t, 5353, sockets[3].LocalPort)
+	assert.Equal(t, "0.0.0.0", sockets[3].LocalAddress.String())
+}
+
+func TestReaderFileDescriptors(t *testing.T) {
+	// GIVEN
+	reader := NewReader("testdata")
+
+	// WHEN
+	fds, err := reader.FileDescriptors(1234)
+
+	// THEN
+	assert.Nil(t, err)
+	assert.Equal(t, FileDescriptors{Pid: 1234, Open: 5, Limit: 1024}, fds)
+}
+
+func TestReaderFileDescriptorsUnlimited(t *testing.T) {
+	// GIVEN
+	reader := NewReader("testdata")
+
+	// WHEN
+	fds, err := reader.FileDescriptors(5678)
+
+	// THEN
+	assert.Nil(t, err)
+	assert.Equal(t, FileDescriptors{Pid: 5678, Open: 10, Limit: 0}, fds)
+}
+
+func TestReaderFileDescriptorsUnknownProcess(t *testing.T) {
+	// GIVEN
+	reader := NewReader("testdata")
+
+	// WHEN
+	_, err := reader.FileDescriptors(4321)
+
+	// THEN
+	assert.NotNil(t, err)
+}
+
+func TestReaderMemTotal(t *testing.T) {
+	// GIVEN
+	reader := NewReader("testdata")
+
+	// WHEN
+	memTotal, err := reader.MemTotal()
+	_, missingErr := NewReader("testdata/1234").MemTotal()
+
+	// THEN
+	// kB are converted to bytes
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2147483648), memTotal)
+	assert.NotNil(t, missingErr)
+}
@@ -0,0 +1,201 @@
+// Package prometheus serves the latest container metrics in the Prometheus text exposition format, for the hosts
+// monitored with Prometheus rather than Elasticsearch.
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/fsouza/go-dockerclient"
+)
+
+const contentType = "text/plain; version=0.0.4"
+
+// metric maps a numeric field of an event type to a Prometheus metric
+type metric struct {
+	eventType string
+	field     string
+	name      string
+	kind      string
+	help      string
+}
+
+var metrics = []metric{
+	{"cpu", "totalUsage", "dockbeat_cpu_usage_ratio", "gauge", "Share of the host CPU time used by the container."},
+	{"cpu", "usageInKernelmode", "dockbeat_cpu_kernelmode_usage_ratio", "gauge", "Share of the host CPU time used by the container in kernel mode."},
+	{"cpu", "usageInUsermode", "dockbeat_cpu_usermode_usage_ratio", "gauge", "Share of the host CPU time used by the container in user mode."},
+	{"memory", "usage", "dockbeat_memory_usage_bytes", "gauge", "Memory usage of the container."},
+	{"memory", "maxUsage", "dockbeat_memory_max_usage_bytes", "gauge", "Maximum memory usage of the container."},
+	{"memory", "totalRss", "dockbeat_memory_rss_bytes", "gauge", "Resident memory of the container."},
+	{"memory", "limit", "dockbeat_memory_limit_bytes", "gauge", "Memory limit of the container."},
+	{"memory", "failcnt", "dockbeat_memory_failcnt_total", "counter", "Number of times the memory usage reached the limit."},
+	{"net", "rxBytes", "dockbeat_net_rx_bytes_total", "counter", "Bytes received by the container on the network."},
+	{"net", "txBytes", "dockbeat_net_tx_bytes_total", "counter", "Bytes sent by the container on the network."},
+	{"net", "rxPackets", "dockbeat_net_rx_packets_total", "counter", "Packets received by the container on the network."},
+	{"net", "txPackets", "dockbeat_net_tx_packets_total", "counter", "Packets sent by the container on the network."},
+	{"net", "rxErrors", "dockbeat_net_rx_errors_total", "counter", "Receive errors of the container on the network."},
+	{"net", "txErrors", "dockbeat_net_tx_errors_total", "counter", "Transmit errors of the container on the network."},
+	{"net", "rxDropped", "dockbeat_net_rx_dropped_total", "counter", "Received packets dropped on the network."},
+	{"net", "txDropped", "dockbeat_net_tx_dropped_total", "counter", "Sent packets dropped on the network."},
+	{"blkio", "readBytes", "dockbeat_blkio_read_bytes_total", "counter", "Bytes read by the container from block devices."},
+	{"blkio", "writeBytes", "dockbeat_blkio_write_bytes_total", "counter", "Bytes written by the container to block devices."},
+	{"blkio", "read", "dockbeat_blkio_reads_total", "counter", "Read operations of the container on block devices."},
+	{"blkio", "write", "dockbeat_blkio_writes_total", "counter", "Write operations of the container on block devices."},
+	{"pids", "current", "dockbeat_pids_current", "gauge", "Number of processes of the container."},
+	{"pids", "limit", "dockbeat_pids_limit", "gauge", "Processes limit of the container, 0 when unlimited."},
+}
+
+var invalidLabelChars = regexp.MustCompile("[^a-zA-Z0-9_]")
+
+// Exporter keeps the latest metrics of each container and serves them over HTTP
+type Exporter struct {
+	sync.RWMutex
+	// Labels are the container labels exported as Prometheus labels
+	Labels []string
+	series map[string][]sample
+}
+
+// sample is the value of a metric for a container, labels being already formatted
+type sample struct {
+	metric *metric
+	labels string
+	value  float64
+}
+
+func NewExporter(labels []string) *Exporter {
+	return &Exporter{Labels: labels, series: map[string][]sample{}}
+}
+
+/*
+Update replaces the metrics of a container with the ones of its events of a tick. Network metrics are labelled with
+the network name. Events of other types and non numeric fields are ignored.
+*/
+func (e *Exporter) Update(container *docker.APIContainers, events []common.MapStr) {
+	labels := e.containerLabels(container)
+	samples := []sample{}
+	for _, event := range events {
+		eventType, _ := event["type"].(string)
+		section, ok := event[eventType].(common.MapStr)
+		if !ok {
+			continue
+		}
+		eventLabels := labels
+		if eventType == "net" {
+			eventLabels = append([]string{formatLabel("network", fmt.Sprint(section["name"]))}, labels...)
+		}
+		for i := range metrics {
+			if metrics[i].eventType != eventType {
+				continue
+			}
+			if value, ok := toFloat(section[metrics[i].field]); ok {
+				samples = append(samples, sample{&metrics[i], strings.Join(eventLabels, ","), value})
+			}
+		}
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	if e.series == nil {
+		e.series = map[string][]sample{}
+	}
+	e.series[container.ID] = samples
+}
+
+// Clean drops the series of the containers which are not in the given set of live container IDs
+func (e *Exporter) Clean(live map[string]bool) {
+	e.Lock()
+	defer e.Unlock()
+	for id := range e.series {
+		if !live[id] {
+			delete(e.series, id)
+		}
+	}
+}
+
+// ServeHTTP writes the metrics of all containers in the text exposition format
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	e.Write(w)
+}
+
+/*
+Write writes the metrics in the text exposition format, grouped by metric as required by the format:
+
+	# HELP dockbeat_memory_usage_bytes Memory usage of the container.
+	# TYPE dockbeat_memory_usage_bytes gauge
+	dockbeat_memory_usage_bytes{id="...",name="web",image="nginx",label_team="core"} 1.2345e+07
+*/
+func (e *Exporter) Write(w io.Writer) error {
+	e.RLock()
+	byMetric := map[*metric][]string{}
+	for _, samples := range e.series {
+		for _, s := range samples {
+			line := s.metric.name + "{" + s.labels + "} " + strconv.FormatFloat(s.value, 'g', -1, 64)
+			byMetric[s.metric] = append(byMetric[s.metric], line)
+		}
+	}
+	e.RUnlock()
+
+	var buffer bytes.Buffer
+	for i := range metrics {
+		lines := byMetric[&metrics[i]]
+		if len(lines) == 0 {
+			continue
+		}
+		sort.Strings(lines)
+		fmt.Fprintf(&buffer, "# HELP %v %v\n# TYPE %v %v\n", metrics[i].name, metrics[i].help, metrics[i].name, metrics[i].kind)
+		for _, line := range lines {
+			buffer.WriteString(line + "\n")
+		}
+	}
+	_, err := buffer.WriteTo(w)
+	return err
+}
+
+func (e *Exporter) containerLabels(container *docker.APIContainers) []string {
+	name := ""
+	if len(container.Names) > 0 {
+		name = strings.TrimPrefix(container.Names[0], "/")
+	}
+	labels := []string{
+		formatLabel("id", container.ID),
+		formatLabel("name", name),
+		formatLabel("image", container.Image),
+	}
+	for _, key := range e.Labels {
+		if value, ok := container.Labels[key]; ok {
+			labels = append(labels, formatLabel("label_"+invalidLabelChars.ReplaceAllString(key, "_"), value))
+		}
+	}
+	return labels
+}
+
+// formatLabel formats a label, escaping backslashes, double quotes and line feeds of the value
+func formatLabel(name string, value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	value = strings.Replace(value, "\n", `\n`, -1)
+	return name + `="` + value + `"`
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,66 @@
+package prometheus
+
+import (
+	"bytes"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExporterWrite(t *testing.T) {
+	// GIVEN
+	exporter := NewExporter([]string{"com.example.team", "missing"})
+	container := &docker.APIContainers{
+		ID:     "container_id",
+		Names:  []string{"/web"},
+		Image:  "nginx",
+		Labels: map[string]string{"com.example.team": `core "1"`, "env": "prod"},
+	}
+
+	// WHEN
+	exporter.Update(container, []common.MapStr{
+		{"type": "memory", "memory": common.MapStr{"usage": uint64(1024), "usage_p": 0.5}},
+		{"type": "net", "net": common.MapStr{"name": "eth0", "rxBytes": uint64(2048)}},
+		{"type": "log", "log": common.MapStr{"message": "ignored"}},
+	})
+	var output bytes.Buffer
+	err := exporter.Write(&output)
+
+	// THEN
+	// only the whitelisted labels are exported
+	assert.Nil(t, err)
+	assert.Equal(t, `# HELP dockbeat_memory_usage_bytes Memory usage of the container.
+# TYPE dockbeat_memory_usage_bytes gauge
+dockbeat_memory_usage_bytes{id="container_id",name="web",image="nginx",label_com_example_team="core \"1\""} 1024
+# HELP dockbeat_net_rx_bytes_total Bytes received by the container on the network.
+# TYPE dockbeat_net_rx_bytes_total counter
+dockbeat_net_rx_bytes_total{network="eth0",id="container_id",name="web",image="nginx",label_com_example_team="core \"1\""} 2048
+`, output.String())
+}
+
+/*
+TestExporterUpdate simulates two ticks of two containers, one of them being removed before the second tick.
+*/
+func TestExporterUpdate(t *testing.T) {
+	// GIVEN
+	exporter := NewExporter(nil)
+	web := docker.APIContainers{ID: "web_id", Names: []string{"/web"}, Image: "nginx"}
+	db := docker.APIContainers{ID: "db_id", Names: []string{"/db"}, Image: "postgres"}
+	exporter.Update(&web, []common.MapStr{{"type": "pids", "pids": common.MapStr{"current": uint64(3)}}})
+	exporter.Update(&db, []common.MapStr{{"type": "pids", "pids": common.MapStr{"current": uint64(12)}}})
+
+	// WHEN
+	exporter.Update(&web, []common.MapStr{{"type": "pids", "pids": common.MapStr{"current": uint64(4)}}})
+	exporter.Clean(map[string]bool{web.ID: true})
+	recorder := httptest.NewRecorder()
+	exporter.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	// THEN
+	assert.Equal(t, contentType, recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `# HELP dockbeat_pids_current Number of processes of the container.
+# TYPE dockbeat_pids_current gauge
+dockbeat_pids_current{id="web_id",name="web",image="nginx"} 4
+`, recorder.Body.String())
+}